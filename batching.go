@@ -0,0 +1,292 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"math/big"
+)
+
+// Recipient of the values opened to all the parties
+const AllParties = PartyID(math.MaxUint64)
+
+// Operations evaluated by opening shared values to all the parties. The openings of all the operations of a layer
+// are batched in a single communication round
+type openingOperation interface {
+	Operation
+	Shares(cep *Protocol) []*big.Int       // returns our shares of the values to open
+	Open(cep *Protocol, opened []*big.Int) // computes the output of the operation from the opened values
+	OpenCount() int                        // returns the number of values opened by the operation
+}
+
+// Opening operations whose values are only opened to one party
+type privateOpeningOperation interface {
+	openingOperation
+	Recipient() PartyID
+}
+
+// Interactive operations opening values over several rounds, each round depending on the values opened in the
+// previous ones. Round k of an operation of layer i is batched with the openings of layer i+k, and its output is
+// available from layer i+OpenRounds()
+type multiRoundOperation interface {
+	Operation
+	OpenRounds() int                                       // returns the number of rounds
+	RoundShares(cep *Protocol, round int) []*big.Int       // returns our shares of the values to open in the round
+	RoundOpen(cep *Protocol, round int, opened []*big.Int) // processes the values opened in the round
+	RoundOpenCount(round int) int                          // returns the number of values opened in the round
+}
+
+// Gates through which a party provides its input
+type inputOperation interface {
+	Operation
+	Owner() PartyID
+	generateShares(cep *Protocol) map[PartyID][]*big.Int // returns the shares of each peer for each output wire
+}
+
+// Operations of a circuit that can be evaluated in the same communication round
+type Layer struct {
+	Inputs   []Operation // input gates, all shared in a single round
+	Local    []Operation // operations computed without communication, in the circuit order
+	Openings []Operation // interactive operations, whose opened values are exchanged in a single round
+	Rounds   []Round     // later rounds of the multi-round operations of the previous layers, batched with the openings
+}
+
+// Round of a multi-round operation started in a previous layer
+type Round struct {
+	Op    multiRoundOperation
+	Index int
+}
+
+// Split the circuit into layers of multiplicative depth: the interactive operations of layer i only depend on the
+// outputs of the layers 0 to i, and their output is available from layer i+1
+func Layers(circuit Circuit) []Layer {
+	var layers []Layer
+	depth := make(map[WireID]int, len(circuit))
+
+	for _, op := range circuit {
+		level := 0
+		for _, in := range op.Inputs() {
+			if depth[in] > level {
+				level = depth[in]
+			}
+		}
+		for len(layers) <= level {
+			layers = append(layers, Layer{})
+		}
+
+		outputLevel := level
+		if _, isInput := op.(inputOperation); isInput {
+			layers[level].Inputs = append(layers[level].Inputs, op)
+		} else if mr, isMultiRound := op.(multiRoundOperation); isMultiRound && mr.OpenRounds() > 0 {
+			layers[level].Openings = append(layers[level].Openings, op)
+			outputLevel = level + mr.OpenRounds()
+			for len(layers) < outputLevel {
+				layers = append(layers, Layer{})
+			}
+			for k := 1; k < mr.OpenRounds(); k++ {
+				layers[level+k].Rounds = append(layers[level+k].Rounds, Round{mr, k})
+			}
+		} else if _, isOpening := op.(openingOperation); isOpening || op.IsMult() {
+			layers[level].Openings = append(layers[level].Openings, op)
+			outputLevel = level + 1
+		} else {
+			layers[level].Local = append(layers[level].Local, op)
+		}
+		for _, w := range outputWires(op) {
+			depth[w] = outputLevel
+		}
+	}
+
+	return layers
+}
+
+// Returns the multiplicative depth of each operation of the circuit, i.e. the maximal number of multiplications on a
+// path from an input to the output of the operation. A multi-round operation counts as one multiplication per round
+func MultiplicativeDepths(circuit Circuit) []int {
+	depths := make([]int, len(circuit))
+	depth := make(map[WireID]int, len(circuit))
+	for i, op := range circuit {
+		level := 0
+		for _, in := range op.Inputs() {
+			if depth[in] > level {
+				level = depth[in]
+			}
+		}
+		if mr, isMultiRound := op.(multiRoundOperation); isMultiRound {
+			level += mr.OpenRounds()
+		} else if op.IsMult() {
+			level++
+		}
+		for _, w := range outputWires(op) {
+			depth[w] = level
+		}
+		depths[i] = level
+	}
+	return depths
+}
+
+// Evaluate all the operations of a layer, using at most one round for the inputs and one round for the openings
+func (cep *Protocol) evalLayer(layer Layer) {
+	if len(layer.Inputs) > 0 {
+		cep.shareInputs(layer.Inputs)
+	}
+
+	for _, op := range layer.Local {
+		op.Eval(cep)
+	}
+
+	var shares []*big.Int
+	var recipients []PartyID
+	var binary []bool
+	var opens []func(opened []*big.Int)
+	var counts []int
+	batch := func(s []*big.Int, recipient PartyID, isBinary bool, open func(opened []*big.Int)) {
+		for range s {
+			recipients = append(recipients, recipient)
+			binary = append(binary, isBinary)
+		}
+		shares = append(shares, s...)
+		opens = append(opens, open)
+		counts = append(counts, len(s))
+	}
+
+	for _, op := range layer.Openings {
+		if oo, isOpening := op.(openingOperation); isOpening {
+			recipient := AllParties
+			if po, isPrivate := op.(privateOpeningOperation); isPrivate {
+				recipient = po.Recipient()
+			}
+			batch(oo.Shares(cep), recipient, isBoolean(op), func(opened []*big.Int) { oo.Open(cep, opened) })
+		} else if mr, isMultiRound := op.(multiRoundOperation); isMultiRound {
+			batch(mr.RoundShares(cep, 0), AllParties, false, func(opened []*big.Int) { mr.RoundOpen(cep, 0, opened) })
+		}
+	}
+	for _, r := range layer.Rounds {
+		r := r
+		batch(r.Op.RoundShares(cep, r.Index), AllParties, false, func(opened []*big.Int) { r.Op.RoundOpen(cep, r.Index, opened) })
+	}
+
+	if len(shares) > 0 {
+		opened := cep.openShares(shares, recipients, binary)
+		for i, open := range opens {
+			open(opened[:counts[i]])
+			opened = opened[counts[i]:]
+		}
+	}
+
+	// Interactive operations that cannot be batched communicate on their own
+	for _, op := range layer.Openings {
+		_, isOpening := op.(openingOperation)
+		_, isMultiRound := op.(multiRoundOperation)
+		if !isOpening && !isMultiRound {
+			op.Eval(cep)
+		}
+	}
+}
+
+// Share all the given inputs in a single round: each party sends to each peer one message with its shares of
+// all the inputs it owns
+func (cep *Protocol) shareInputs(inputs []Operation) {
+	outgoing := make(map[PartyID][]uint64, len(cep.Peers))
+	incoming := make(map[PartyID][]WireID, len(cep.Peers))
+
+	for _, op := range inputs {
+		owner := op.(inputOperation).Owner()
+		if owner == cep.ID {
+			for id, shares := range op.(inputOperation).generateShares(cep) {
+				for _, share := range shares {
+					outgoing[id] = append(outgoing[id], share.Uint64())
+				}
+			}
+		} else {
+			incoming[owner] = append(incoming[owner], outputWires(op)...)
+		}
+	}
+
+	for id, values := range outgoing {
+		cep.Peers[id].SendingChan <- Message{BatchMessage: &BatchMessage{Values: values}}
+	}
+
+	for id, wires := range incoming {
+		values := cep.receiveBatch(cep.Peers[id], len(wires))
+		for i, wire := range wires {
+			cep.WireOutput[wire] = new(big.Int).SetUint64(values[i])
+		}
+	}
+
+	cep.Rounds++
+}
+
+// Open the given shared values in a single round: our shares are sent to their recipients (AllParties or a single
+// party) in one message per peer, and the shares received from each peer are summed up modulo q, or XORed for the
+// words of boolean gates, flagged in 'binary' (nil if there is none). The values that are not opened to us are nil
+func (cep *Protocol) openShares(shares []*big.Int, recipients []PartyID, binary []bool) []*big.Int {
+	reduce := func(i int, share *big.Int) *big.Int {
+		if binary != nil && binary[i] {
+			return new(big.Int).Set(share)
+		}
+		return new(big.Int).Mod(share, q)
+	}
+
+	opened := make([]*big.Int, len(shares))
+	expected := 0
+	for i, share := range shares {
+		if recipients[i] == AllParties || recipients[i] == cep.ID {
+			opened[i] = reduce(i, share)
+			expected++
+		}
+	}
+
+	for _, peer := range cep.Peers {
+		if peer.ID != cep.ID {
+			var values []uint64
+			for i, share := range shares {
+				if recipients[i] == AllParties || recipients[i] == peer.ID {
+					values = append(values, reduce(i, share).Uint64())
+				}
+			}
+			if len(values) > 0 {
+				peer.SendingChan <- Message{BatchMessage: &BatchMessage{Values: values}}
+			}
+		}
+	}
+
+	if expected > 0 {
+		for _, peer := range cep.Peers {
+			if peer.ID != cep.ID {
+				values := cep.receiveBatch(peer, expected)
+				for i := range opened {
+					if opened[i] == nil {
+						continue
+					}
+					if binary != nil && binary[i] {
+						opened[i].Xor(opened[i], new(big.Int).SetUint64(values[0]))
+					} else {
+						opened[i].Add(opened[i], new(big.Int).SetUint64(values[0]))
+					}
+					values = values[1:]
+				}
+			}
+		}
+	}
+
+	for i, value := range opened {
+		if value != nil {
+			opened[i] = reduce(i, value)
+		}
+	}
+
+	cep.Rounds++
+	return opened
+}
+
+// Wait for the next batch of 'size' values sent by the peer
+func (cep *Protocol) receiveBatch(peer *RemoteParty, size int) []uint64 {
+	m := <-peer.ReceiveChan
+	if m.BatchMessage == nil {
+		check(errors.New("unexpected message received instead of BatchMessage"))
+	}
+	if len(m.BatchMessage.Values) != size {
+		check(errors.New("unexpected number of values in BatchMessage"))
+	}
+	return m.BatchMessage.Values
+}
@@ -51,6 +51,14 @@ func (cep *BeaverProtocol) Run() {
 
 }
 
+// Start the generation of triplets whose a and b are shared as 'ai' and 'bi' instead of being random, each holding
+// 1<<params.logN values. It is used to generate structured triplets, such as the matrix triplets
+func (cep *BeaverProtocol) RunWithShares(ai, bi []uint64) {
+	cep.generateTriplets(ai, bi)
+	cep.ReceiveOtherBeaver()
+	cep.ComputeC()
+}
+
 // Last 'round' of Beaver's triplet generation protocol:
 // compute our share of c_i
 func (cep *BeaverProtocol) ComputeC() {
@@ -136,10 +144,12 @@ func (cep *BeaverProtocol) ReceiveOtherBeaver() {
 // First 'round' of Beaver's triplet generation protocol:
 // generate our values (a, b, secret key, ...)
 func (cep *BeaverProtocol) GenerateTriplets() {
+	cep.generateTriplets(newRandomVec(1<<cep.Params.LogN, cep.Params.T), newRandomVec(1<<cep.Params.LogN, cep.Params.T))
+}
+
+func (cep *BeaverProtocol) generateTriplets(ai, bi []uint64) {
 	keyGen := bfv.NewKeyGenerator(cep.Params)
 
-	ai := newRandomVec(1<<cep.Params.LogN, cep.Params.T)
-	bi := newRandomVec(1<<cep.Params.LogN, cep.Params.T)
 	ci := mulVec(ai, bi, cep.Params.T)
 
 	aiPt := bfv.NewPlaintext(cep.Params)
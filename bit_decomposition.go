@@ -0,0 +1,253 @@
+package main
+
+import (
+	"math/big"
+)
+
+// Shared bits of In, from the least significant one, written to the wires Out to Out+m-1 where m is the bit length of
+// q. With r a uniform r < q whose m bits r_i are preprocessed, c = In+r mod q is opened, so that In = c-r if c >= r and
+// c+q-r otherwise. The bits of both differences, computed modulo 2^m, are K_i XOR r_i XOR borrow_i for K = c and
+// K = c+q, where borrow_i is the comparison of the bits 0 to i-1 of K and r. The borrows are computed with a parallel
+// prefix scan of log2(m) rounds over the same (a, b) pairs as in the LessThan gates, and the last borrow of c gives
+// [c < r], which selects the bits of one of the differences. The gate takes 3+log2(m) rounds, m random bits and about
+// 9m triplets
+type BitDecompose struct {
+	In  WireID
+	Out WireID
+}
+
+// Pairs (i, p) of the prefix scan at each level: the bits i having the bit l of their index set are combined with the
+// last bit p of the lower half of their block of size 2^(l+1), so that after level l, bit i covers the bits from the
+// start of its block of size 2^(l+1)
+func prefixScanLevels() [][][2]int {
+	var levels [][][2]int
+	for l := uint(0); 1<<l < comparisonBits; l++ {
+		var pairs [][2]int
+		for i := 0; i < comparisonBits; i++ {
+			if i&(1<<l) != 0 {
+				pairs = append(pairs, [2]int{i, i>>l<<l - 1})
+			}
+		}
+		levels = append(levels, pairs)
+	}
+	return levels
+}
+
+var bitDecomposeLevels = prefixScanLevels()
+
+// Number of multiplications of a pair at level l: a_i = a_i + b_i*a_p, and b_i = b_i*b_p unless the block of bit i
+// starts at bit 0, whose b is never needed
+func scanMults(pair [2]int, l int) int {
+	if pair[0] < 2<<uint(l) {
+		return 1
+	}
+	return 2
+}
+
+// Intermediate values of the subtraction from K = c (j = 0) and K = c+q (j = 1), stored in the internal wires after
+// the output bits: the prefix comparisons a and b of bit i, and K_i XOR r_i, replaced by the bit i of the difference
+func (bdo BitDecompose) a(j, i int) WireID {
+	return bdo.Out + WireID((1+j)*comparisonBits+i)
+}
+
+func (bdo BitDecompose) b(j, i int) WireID {
+	return bdo.Out + WireID((3+j)*comparisonBits+i)
+}
+
+func (bdo BitDecompose) t(j, i int) WireID {
+	return bdo.Out + WireID((5+j)*comparisonBits+i)
+}
+
+// The internal wires hold the m-1 other output bits and the intermediate values, and key the triplets from Out+1
+func (bdo BitDecompose) InternalWires() int {
+	if triplets := bdo.firstTriplet(bdo.OpenRounds()); triplets > 7*comparisonBits-1 {
+		return triplets
+	}
+	return 7*comparisonBits - 1
+}
+
+// Returns the index of the first triplet used in the round
+func (bdo BitDecompose) firstTriplet(round int) int {
+	index := 0
+	for k := 1; k < round; k++ {
+		index += bdo.RoundOpenCount(k) / 2
+	}
+	return index
+}
+
+func (bdo BitDecompose) triplet(cep *Protocol, index int) BeaverTriplet {
+	return cep.BeaverTriplets[bdo.Out+1+WireID(index)]
+}
+
+func (bdo BitDecompose) TripletWires() []WireID {
+	return vectorWires(bdo.Out+1, bdo.firstTriplet(bdo.OpenRounds()))
+}
+
+func (bdo BitDecompose) RandomBitWires() []WireID {
+	return vectorWires(bdo.Out+1, comparisonBits)
+}
+
+func (bdo BitDecompose) FieldBits() bool {
+	return true
+}
+
+func (bdo BitDecompose) IsMult() bool {
+	return true
+}
+
+func (bdo BitDecompose) Output() WireID {
+	return bdo.Out
+}
+
+func (bdo BitDecompose) Inputs() []WireID {
+	return []WireID{bdo.In}
+}
+
+func (bdo BitDecompose) Remap(f func(WireID) WireID) Operation {
+	return &BitDecompose{In: f(bdo.In), Out: f(bdo.Out)}
+}
+
+func (bdo BitDecompose) Eval(cep *Protocol) {
+	evalRounds(cep, bdo)
+}
+
+func (bdo BitDecompose) EvalClear(ce *ClearEvaluation) {
+	x := new(big.Int).Mod(ce.Wires[bdo.In], q)
+	for i := 0; i < comparisonBits; i++ {
+		ce.Wires[bdo.Out+WireID(i)] = big.NewInt(int64(x.Bit(i)))
+	}
+}
+
+// Round 0 opens c, the rounds 1 to log2(m) compute the borrows, the next one the bits of the differences and the last
+// one selects the bits of In
+func (bdo BitDecompose) OpenRounds() int {
+	return len(bitDecomposeLevels) + 3
+}
+
+func (bdo BitDecompose) RoundOpenCount(round int) int {
+	switch {
+	case round == 0:
+		return 1
+	case round <= len(bitDecomposeLevels):
+		mults := 0
+		for _, pair := range bitDecomposeLevels[round-1] {
+			mults += scanMults(pair, round-1)
+		}
+		return 2 * 2 * mults
+	case round == len(bitDecomposeLevels)+1:
+		return 2 * 2 * (comparisonBits - 1)
+	default:
+		return 2 * comparisonBits
+	}
+}
+
+func (bdo BitDecompose) RoundShares(cep *Protocol, round int) []*big.Int {
+	var shares []*big.Int
+	next := bdo.firstTriplet(round)
+	mult := func(x, y WireID) {
+		shares = append(shares, beaverShares(cep.WireOutput[x], cep.WireOutput[y], bdo.triplet(cep, next))...)
+		next++
+	}
+
+	switch {
+	case round == 0:
+		shares = append(shares, maskShare(cep, cep.WireOutput[bdo.In], bdo.RandomBitWires()))
+	case round <= len(bitDecomposeLevels):
+		for j := 0; j < 2; j++ {
+			for _, pair := range bitDecomposeLevels[round-1] {
+				i, p := pair[0], pair[1]
+				mult(bdo.b(j, i), bdo.a(j, p))
+				if scanMults(pair, round-1) == 2 {
+					mult(bdo.b(j, i), bdo.b(j, p))
+				}
+			}
+		}
+	case round == len(bitDecomposeLevels)+1:
+		for j := 0; j < 2; j++ {
+			for i := 1; i < comparisonBits; i++ {
+				mult(bdo.t(j, i), bdo.a(j, i-1))
+			}
+		}
+	default:
+		for i := 0; i < comparisonBits; i++ {
+			diff := new(big.Int).Sub(cep.WireOutput[bdo.t(1, i)], cep.WireOutput[bdo.t(0, i)])
+			shares = append(shares, beaverShares(cep.WireOutput[bdo.a(0, comparisonBits-1)], diff, bdo.triplet(cep, next))...)
+			next++
+		}
+	}
+	return shares
+}
+
+func (bdo BitDecompose) RoundOpen(cep *Protocol, round int, opened []*big.Int) {
+	next := bdo.firstTriplet(round)
+	product := func(x, y *big.Int) *big.Int {
+		z := beaverProduct(cep, x, y, bdo.triplet(cep, next), opened[:2])
+		opened = opened[2:]
+		next++
+		return z
+	}
+
+	switch {
+	case round == 0:
+		// With K public, K_i XOR r_i and the comparison [K_i < r_i] are affine in r_i, as in the LessThan gates
+		c := opened[0]
+		k := [2]*big.Int{c, new(big.Int).Add(c, q)}
+		for j := 0; j < 2; j++ {
+			for i, w := range bdo.RandomBitWires() {
+				r := cep.RandomBits[w]
+				notR := new(big.Int).Sub(constantShare(cep, 1), r)
+				if k[j].Bit(i) == 0 {
+					cep.WireOutput[bdo.a(j, i)] = new(big.Int).Set(r)
+					cep.WireOutput[bdo.b(j, i)] = notR
+					cep.WireOutput[bdo.t(j, i)] = new(big.Int).Set(r)
+				} else {
+					cep.WireOutput[bdo.a(j, i)] = big.NewInt(0)
+					cep.WireOutput[bdo.b(j, i)] = new(big.Int).Set(r)
+					cep.WireOutput[bdo.t(j, i)] = new(big.Int).Set(notR)
+				}
+			}
+		}
+	case round <= len(bitDecomposeLevels):
+		for j := 0; j < 2; j++ {
+			for _, pair := range bitDecomposeLevels[round-1] {
+				i, p := pair[0], pair[1]
+				bi := cep.WireOutput[bdo.b(j, i)]
+				a := product(bi, cep.WireOutput[bdo.a(j, p)])
+				a.Add(a, cep.WireOutput[bdo.a(j, i)])
+				cep.WireOutput[bdo.a(j, i)] = a.Mod(a, q)
+				if scanMults(pair, round-1) == 2 {
+					cep.WireOutput[bdo.b(j, i)] = product(bi, cep.WireOutput[bdo.b(j, p)])
+				}
+			}
+		}
+	case round == len(bitDecomposeLevels)+1:
+		// t XOR borrow = t + borrow - 2*t*borrow, the borrow of bit 0 being 0
+		for j := 0; j < 2; j++ {
+			for i := 1; i < comparisonBits; i++ {
+				t, borrow := cep.WireOutput[bdo.t(j, i)], cep.WireOutput[bdo.a(j, i-1)]
+				z := product(t, borrow)
+				z.Lsh(z, 1)
+				z.Sub(new(big.Int).Add(t, borrow), z)
+				cep.WireOutput[bdo.t(j, i)] = z.Mod(z, q)
+			}
+		}
+	default:
+		// The difference from c if c >= r, i.e. its last borrow [c < r] is 0, and from c+q otherwise
+		for i := 0; i < comparisonBits; i++ {
+			diff := new(big.Int).Sub(cep.WireOutput[bdo.t(1, i)], cep.WireOutput[bdo.t(0, i)])
+			z := product(cep.WireOutput[bdo.a(0, comparisonBits-1)], diff)
+			z.Add(z, cep.WireOutput[bdo.t(0, i)])
+			cep.WireOutput[bdo.Out+WireID(i)] = z.Mod(z, q)
+		}
+	}
+}
+
+func (bdo BitDecompose) BeaverTriplet(count int) []BeaverTriplet {
+	return Mult{}.BeaverTriplet(count)
+}
+
+// Shared bits of x, from the least significant one, as a vector of comparisonBits wires
+func (x Wire) Bits() Vector {
+	bits := x.b.emit(func(out WireID) Operation { return &BitDecompose{In: x.ID, Out: out} })
+	return Vector{x.b, bits.ID, comparisonBits}
+}
@@ -0,0 +1,408 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"math"
+	"math/big"
+	mathrand "math/rand"
+)
+
+// Boolean gates work on words of 64 bits XOR-shared between the parties, instead of values additively shared modulo
+// q: each wire holds 64 independent bits, evaluated in parallel by every gate. Xor and Not are computed locally, And
+// consumes a binary triplet. Boolean and arithmetic wires cannot be mixed (see MismatchedSharing)
+type booleanOperation interface {
+	Operation
+	isBoolean()
+}
+
+// Shares of 64 binary Beaver triplets (a, b, c = a AND b), one per bit of the words
+type BinaryTriplet struct {
+	a uint64
+	b uint64
+	c uint64
+}
+
+// Operations consuming a binary triplet, keyed by their output wire
+type binaryTripletOperation interface {
+	booleanOperation
+	BinaryTriplet(count int, random func() uint64) []BinaryTriplet // returns the shares of a new binary triplet for the parties 0 to count-1
+}
+
+// Returns a uniformly random word
+func randomWord() uint64 {
+	var buf [8]byte
+	_, err := rand.Read(buf[:])
+	check(err)
+	return binary.BigEndian.Uint64(buf[:])
+}
+
+// Split the word into 'count' XOR shares, drawn from 'random' except the last one
+func shareWord(value uint64, count int, random func() uint64) []uint64 {
+	shares := make([]uint64, count)
+	shares[count-1] = value
+	for i := 0; i < count-1; i++ {
+		shares[i] = random()
+		shares[count-1] ^= shares[i]
+	}
+	return shares
+}
+
+// Input word of 64 bits provided by the party, XOR-shared between the parties
+type BoolInput struct {
+	Party PartyID
+	Out   WireID
+}
+
+func (bio BoolInput) isBoolean() {}
+
+func (bio BoolInput) IsMult() bool {
+	return false
+}
+
+func (bio BoolInput) Output() WireID {
+	return bio.Out
+}
+
+func (bio BoolInput) Inputs() []WireID {
+	return nil
+}
+
+func (bio BoolInput) Remap(f func(WireID) WireID) Operation {
+	return &BoolInput{Party: bio.Party, Out: f(bio.Out)}
+}
+
+func (bio BoolInput) Owner() PartyID {
+	return bio.Party
+}
+
+// Split our input word into random XOR shares: keep our share and return the share of each peer
+func (bio BoolInput) generateShares(cep *Protocol) map[PartyID][]*big.Int {
+	shares := make(map[PartyID][]*big.Int, len(cep.Peers))
+	word := cep.Inputs[GateID(bio.Out)]
+	for _, peer := range cep.Peers {
+		if peer.ID != cep.ID {
+			share := randomWord()
+			word ^= share
+			shares[peer.ID] = []*big.Int{new(big.Int).SetUint64(share)}
+		}
+	}
+	cep.WireOutput[bio.Out] = new(big.Int).SetUint64(word)
+	return shares
+}
+
+func (bio BoolInput) Eval(cep *Protocol) {
+	cep.shareInputs([]Operation{bio})
+}
+
+func (bio BoolInput) EvalClear(ce *ClearEvaluation) {
+	ce.Wires[bio.Out] = new(big.Int).SetUint64(ce.Inputs[bio.Party][GateID(bio.Out)])
+}
+
+func (bio BoolInput) BeaverTriplet(count int) []BeaverTriplet {
+	return nil
+}
+
+// Bitwise XOR of the words In1 and In2, computed locally on the shares
+type Xor struct {
+	In1 WireID
+	In2 WireID
+	Out WireID
+}
+
+func (xo Xor) isBoolean() {}
+
+func (xo Xor) IsMult() bool {
+	return false
+}
+
+func (xo Xor) Output() WireID {
+	return xo.Out
+}
+
+func (xo Xor) Inputs() []WireID {
+	return []WireID{xo.In1, xo.In2}
+}
+
+func (xo Xor) Remap(f func(WireID) WireID) Operation {
+	return &Xor{In1: f(xo.In1), In2: f(xo.In2), Out: f(xo.Out)}
+}
+
+func (xo Xor) Eval(cep *Protocol) {
+	cep.WireOutput[xo.Out] = new(big.Int).Xor(cep.WireOutput[xo.In1], cep.WireOutput[xo.In2])
+}
+
+func (xo Xor) EvalClear(ce *ClearEvaluation) {
+	ce.Wires[xo.Out] = new(big.Int).Xor(ce.Wires[xo.In1], ce.Wires[xo.In2])
+}
+
+func (xo Xor) BeaverTriplet(count int) []BeaverTriplet {
+	return nil
+}
+
+// Bitwise negation of the word In, computed locally: party 0 flips all the bits of its share
+type Not struct {
+	In  WireID
+	Out WireID
+}
+
+// Word with all the bits set
+var allOnes = new(big.Int).SetUint64(math.MaxUint64)
+
+func (no Not) isBoolean() {}
+
+func (no Not) IsMult() bool {
+	return false
+}
+
+func (no Not) Output() WireID {
+	return no.Out
+}
+
+func (no Not) Inputs() []WireID {
+	return []WireID{no.In}
+}
+
+func (no Not) Remap(f func(WireID) WireID) Operation {
+	return &Not{In: f(no.In), Out: f(no.Out)}
+}
+
+func (no Not) Eval(cep *Protocol) {
+	cep.WireOutput[no.Out] = new(big.Int).Set(cep.WireOutput[no.In])
+	if cep.ID == 0 {
+		cep.WireOutput[no.Out].Xor(cep.WireOutput[no.Out], allOnes)
+	}
+}
+
+func (no Not) EvalClear(ce *ClearEvaluation) {
+	ce.Wires[no.Out] = new(big.Int).Xor(ce.Wires[no.In], allOnes)
+}
+
+func (no Not) BeaverTriplet(count int) []BeaverTriplet {
+	return nil
+}
+
+// Bitwise AND of the words In1 and In2, with the binary triplet (a, b, c) keyed by Out: d = In1^a and e = In2^b are
+// opened in one round, and our share of In1&In2 is c ^ (d&In2) ^ (e&In1), party 0 also adding d&e
+type And struct {
+	In1 WireID
+	In2 WireID
+	Out WireID
+}
+
+func (ao And) isBoolean() {}
+
+func (ao And) IsMult() bool {
+	return true
+}
+
+// The AND consumes a binary triplet instead of a Beaver triplet
+func (ao And) TripletWires() []WireID {
+	return nil
+}
+
+func (ao And) Output() WireID {
+	return ao.Out
+}
+
+func (ao And) Inputs() []WireID {
+	return []WireID{ao.In1, ao.In2}
+}
+
+func (ao And) Remap(f func(WireID) WireID) Operation {
+	return &And{In1: f(ao.In1), In2: f(ao.In2), Out: f(ao.Out)}
+}
+
+func (ao And) Eval(cep *Protocol) {
+	evalOpening(cep, ao)
+}
+
+func (ao And) EvalClear(ce *ClearEvaluation) {
+	ce.Wires[ao.Out] = new(big.Int).And(ce.Wires[ao.In1], ce.Wires[ao.In2])
+}
+
+// Returns our shares of In1^a and In2^b
+func (ao And) Shares(cep *Protocol) []*big.Int {
+	triplet := cep.BinaryTriplets[ao.Out]
+	return []*big.Int{
+		new(big.Int).SetUint64(cep.WireOutput[ao.In1].Uint64() ^ triplet.a),
+		new(big.Int).SetUint64(cep.WireOutput[ao.In2].Uint64() ^ triplet.b),
+	}
+}
+
+// Computes our share of In1&In2 from the opened d = In1^a and e = In2^b
+func (ao And) Open(cep *Protocol, opened []*big.Int) {
+	x, y := cep.WireOutput[ao.In1].Uint64(), cep.WireOutput[ao.In2].Uint64()
+	d, e := opened[0].Uint64(), opened[1].Uint64()
+	z := cep.BinaryTriplets[ao.Out].c ^ d&y ^ e&x
+	if cep.ID == 0 {
+		z ^= d & e
+	}
+	cep.WireOutput[ao.Out] = new(big.Int).SetUint64(z)
+}
+
+func (ao And) OpenCount() int {
+	return 2
+}
+
+func (ao And) BeaverTriplet(count int) []BeaverTriplet {
+	return nil
+}
+
+// Returns the XOR shares of random words a and b and of c = a AND b
+func (ao And) BinaryTriplet(count int, random func() uint64) []BinaryTriplet {
+	a, b := random(), random()
+	aShares, bShares, cShares := shareWord(a, count, random), shareWord(b, count, random), shareWord(a&b, count, random)
+	triplets := make([]BinaryTriplet, count)
+	for i := range triplets {
+		triplets[i] = BinaryTriplet{a: aShares[i], b: bShares[i], c: cShares[i]}
+	}
+	return triplets
+}
+
+// Reveal the word In to all the parties, by XORing all the shares together
+type BoolReveal struct {
+	In  WireID
+	Out WireID
+}
+
+func (bro BoolReveal) isBoolean() {}
+
+func (bro BoolReveal) IsMult() bool {
+	return false
+}
+
+func (bro BoolReveal) Output() WireID {
+	return bro.Out
+}
+
+func (bro BoolReveal) Inputs() []WireID {
+	return []WireID{bro.In}
+}
+
+func (bro BoolReveal) Remap(f func(WireID) WireID) Operation {
+	return &BoolReveal{In: f(bro.In), Out: f(bro.Out)}
+}
+
+func (bro BoolReveal) Eval(cep *Protocol) {
+	evalOpening(cep, bro)
+}
+
+func (bro BoolReveal) EvalClear(ce *ClearEvaluation) {
+	ce.Wires[bro.Out] = new(big.Int).Set(ce.Wires[bro.In])
+	ce.Outputs[bro.Out] = ce.Wires[bro.Out].Uint64()
+}
+
+// Returns our share of the revealed word
+func (bro BoolReveal) Shares(cep *Protocol) []*big.Int {
+	return []*big.Int{new(big.Int).Set(cep.WireOutput[bro.In])}
+}
+
+// Stores the revealed word in the wire and in the outputs of the protocol
+func (bro BoolReveal) Open(cep *Protocol, opened []*big.Int) {
+	cep.WireOutput[bro.Out] = new(big.Int).Set(opened[0])
+	cep.Outputs[bro.Out] = opened[0].Uint64()
+}
+
+func (bro BoolReveal) OpenCount() int {
+	return 1
+}
+
+func (bro BoolReveal) BeaverTriplet(count int) []BeaverTriplet {
+	return nil
+}
+
+// Returns true if the operation works on XOR-shared words
+func isBoolean(op Operation) bool {
+	_, isBool := op.(booleanOperation)
+	return isBool
+}
+
+// Returns true if the circuit has operations consuming binary triplets
+func hasBinaryTriplets(circuit Circuit) bool {
+	for _, op := range circuit {
+		if _, isBinary := op.(binaryTripletOperation); isBinary {
+			return true
+		}
+	}
+	return false
+}
+
+// Generate the binary triplets of all the AND gates of the circuit, for the parties 0 to parties-1
+func DealBinaryTriplets(circuit Circuit, parties int) map[PartyID]map[WireID]BinaryTriplet {
+	return dealBinaryTriplets(circuit, parties, randomWord)
+}
+
+func dealBinaryTriplets(circuit Circuit, parties int, random func() uint64) map[PartyID]map[WireID]BinaryTriplet {
+	binaryTriplets := make(map[PartyID]map[WireID]BinaryTriplet, parties)
+	for id := 0; id < parties; id++ {
+		binaryTriplets[PartyID(id)] = make(map[WireID]BinaryTriplet)
+	}
+	for _, op := range circuit {
+		if bo, isBinary := op.(binaryTripletOperation); isBinary {
+			for id, triplet := range bo.BinaryTriplet(parties, random) {
+				binaryTriplets[PartyID(id)][op.Output()] = triplet
+			}
+		}
+	}
+	return binaryTriplets
+}
+
+// Dealer run locally by each party, without any communication nor oblivious transfer: all the parties derive the
+// same binary triplets from the common Seed, and keep their own shares. Since every party can derive the shares of
+// the others, it is only meant for tests and benchmarks, until the binary triplets are generated with OT
+type LocalBinaryDealer struct {
+	Seed int64
+}
+
+// Returns the shares of the party of the binary triplets of all the AND gates of the circuit, evaluated by the
+// parties 0 to parties-1
+func (d LocalBinaryDealer) Triplets(circuit Circuit, party PartyID, parties int) map[WireID]BinaryTriplet {
+	return dealBinaryTriplets(circuit, parties, mathrand.New(mathrand.NewSource(d.Seed)).Uint64)[party]
+}
+
+// Handle on a boolean wire of the circuit being built by a CircuitBuilder, holding a word of 64 XOR-shared bits
+type BoolWire struct {
+	b  *CircuitBuilder
+	ID WireID
+}
+
+func (b *CircuitBuilder) emitBool(newOp func(out WireID) Operation) BoolWire {
+	return BoolWire{b, b.emit(newOp).ID}
+}
+
+func (b *CircuitBuilder) checkBool(w BoolWire) {
+	if w.b != b {
+		panic("wire belongs to another circuit")
+	}
+}
+
+// Add an input word provided by the party
+func (b *CircuitBuilder) BoolInput(party PartyID) BoolWire {
+	return b.emitBool(func(out WireID) Operation { return &BoolInput{Party: party, Out: out} })
+}
+
+// Reveal the word to all the parties, and return the wire holding the revealed word
+func (b *CircuitBuilder) RevealBool(w BoolWire) BoolWire {
+	b.checkBool(w)
+	return b.emitBool(func(out WireID) Operation { return &BoolReveal{In: w.ID, Out: out} })
+}
+
+func (x BoolWire) Xor(y BoolWire) BoolWire {
+	x.b.checkBool(y)
+	return x.b.emitBool(func(out WireID) Operation { return &Xor{In1: x.ID, In2: y.ID, Out: out} })
+}
+
+func (x BoolWire) And(y BoolWire) BoolWire {
+	x.b.checkBool(y)
+	return x.b.emitBool(func(out WireID) Operation { return &And{In1: x.ID, In2: y.ID, Out: out} })
+}
+
+func (x BoolWire) Not() BoolWire {
+	return x.b.emitBool(func(out WireID) Operation { return &Not{In: x.ID, Out: out} })
+}
+
+// Bitwise OR, computed as x ^ y ^ (x & y) with a single AND gate
+func (x BoolWire) Or(y BoolWire) BoolWire {
+	return x.Xor(y).Xor(x.And(y))
+}
@@ -0,0 +1,157 @@
+package main
+
+// Helper to construct circuits programmatically: each operation allocates a new wire, so that wire IDs never collide
+type CircuitBuilder struct {
+	circuit Circuit
+	next    WireID
+}
+
+// Handle on a wire of the circuit being built by a CircuitBuilder
+type Wire struct {
+	b  *CircuitBuilder
+	ID WireID
+}
+
+// Create a builder for an empty circuit, whose wires are allocated from 0
+func NewCircuitBuilder() *CircuitBuilder {
+	return &CircuitBuilder{}
+}
+
+// Append the operation computing a new wire and return its handle. 'newOp' receives the allocated wire, followed
+// by the internal wires of the operation if any
+func (b *CircuitBuilder) emit(newOp func(out WireID) Operation) Wire {
+	out := b.next
+	op := newOp(out)
+	b.next += WireID(len(outputWires(op)))
+	b.circuit = append(b.circuit, op)
+	return Wire{b, out}
+}
+
+// Allocate a new wire, without any operation computing it
+func (b *CircuitBuilder) alloc() WireID {
+	out := b.next
+	b.next++
+	return out
+}
+
+func (b *CircuitBuilder) check(w Wire) {
+	if w.b != b {
+		panic("wire belongs to another circuit")
+	}
+}
+
+// Returns the circuit built so far
+func (b *CircuitBuilder) Circuit() Circuit {
+	return append(Circuit(nil), b.circuit...)
+}
+
+// Add an input provided by the party
+func (b *CircuitBuilder) Input(party PartyID) Wire {
+	return b.emit(func(out WireID) Operation { return &Input{Party: party, Out: out} })
+}
+
+// Reveal the wire to all the parties, and return the wire holding the revealed value
+func (b *CircuitBuilder) Reveal(w Wire) Wire {
+	b.check(w)
+	return b.emit(func(out WireID) Operation { return &Reveal{In: w.ID, Out: out} })
+}
+
+// Reveal the wire to the party only, and return the wire holding the revealed value
+func (b *CircuitBuilder) RevealTo(w Wire, party PartyID) Wire {
+	b.check(w)
+	return b.emit(func(out WireID) Operation { return &RevealTo{In: w.ID, Out: out, Party: party} })
+}
+
+func (x Wire) Add(y Wire) Wire {
+	x.b.check(y)
+	return x.b.emit(func(out WireID) Operation { return &Add{In1: x.ID, In2: y.ID, Out: out} })
+}
+
+func (x Wire) Sub(y Wire) Wire {
+	x.b.check(y)
+	return x.b.emit(func(out WireID) Operation { return &Sub{In1: x.ID, In2: y.ID, Out: out} })
+}
+
+func (x Wire) Mul(y Wire) Wire {
+	x.b.check(y)
+	return x.b.emit(func(out WireID) Operation { return &Mult{In1: x.ID, In2: y.ID, Out: out} })
+}
+
+func (x Wire) AddConst(cst uint64) Wire {
+	return x.b.emit(func(out WireID) Operation { return &AddCst{In: x.ID, CstValue: cst, Out: out} })
+}
+
+func (x Wire) MulConst(cst uint64) Wire {
+	return x.b.emit(func(out WireID) Operation { return &MultCst{In: x.ID, CstValue: cst, Out: out} })
+}
+
+// Handle on a vector wire of the circuit being built by a CircuitBuilder, whose elements are the wires ID to
+// ID+Len-1
+type Vector struct {
+	b   *CircuitBuilder
+	ID  WireID
+	Len int
+}
+
+// Append the operation computing a new vector of n elements and return its handle
+func (b *CircuitBuilder) emitVector(n int, newOp func(out WireID) Operation) Vector {
+	out := b.next
+	b.next += WireID(n)
+	b.circuit = append(b.circuit, newOp(out))
+	return Vector{b, out, n}
+}
+
+func (b *CircuitBuilder) checkVector(v Vector) {
+	if v.b != b {
+		panic("vector belongs to another circuit")
+	}
+}
+
+func (x Vector) checkLen(y Vector) {
+	x.b.checkVector(y)
+	if x.Len != y.Len {
+		panic("vectors of different lengths")
+	}
+}
+
+// Add a vector of n inputs provided by the party
+func (b *CircuitBuilder) VectorInput(party PartyID, n int) Vector {
+	return b.emitVector(n, func(out WireID) Operation { return &VecInput{Party: party, Out: out, Len: n} })
+}
+
+// Reveal all the elements of the vector to all the parties, and return the vector holding the revealed values
+func (b *CircuitBuilder) RevealVector(v Vector) Vector {
+	b.checkVector(v)
+	return b.emitVector(v.Len, func(out WireID) Operation { return &VecReveal{In: v.ID, Out: out, Len: v.Len} })
+}
+
+// Returns the handle of the i-th element of the vector, to be used in scalar gates
+func (x Vector) Elem(i int) Wire {
+	if i < 0 || i >= x.Len {
+		panic("index out of range")
+	}
+	return Wire{x.b, x.ID + WireID(i)}
+}
+
+func (x Vector) Add(y Vector) Vector {
+	x.checkLen(y)
+	return x.b.emitVector(x.Len, func(out WireID) Operation { return &VecAdd{In1: x.ID, In2: y.ID, Out: out, Len: x.Len} })
+}
+
+func (x Vector) Sub(y Vector) Vector {
+	x.checkLen(y)
+	return x.b.emitVector(x.Len, func(out WireID) Operation { return &VecSub{In1: x.ID, In2: y.ID, Out: out, Len: x.Len} })
+}
+
+func (x Vector) Mul(y Vector) Vector {
+	x.checkLen(y)
+	return x.b.emitVector(x.Len, func(out WireID) Operation { return &VecMult{In1: x.ID, In2: y.ID, Out: out, Len: x.Len} })
+}
+
+func (x Vector) AddConst(cst uint64) Vector {
+	return x.b.emitVector(x.Len, func(out WireID) Operation { return &VecAddCst{In: x.ID, CstValue: cst, Out: out, Len: x.Len} })
+}
+
+func (x Vector) MulConst(cst uint64) Vector {
+	return x.b.emitVector(x.Len, func(out WireID) Operation { return &VecMultCst{In: x.ID, CstValue: cst, Out: out, Len: x.Len} })
+}
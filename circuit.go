@@ -1,3 +1,26 @@
 package main
 
 type Circuit []Operation // Circuit definition
+
+// Returns the output wires of the Reveal, VecReveal and BoolReveal gates (public outputs), in the order of the circuit
+func (c Circuit) OutputWires() []WireID {
+	var wires []WireID
+	for _, op := range c {
+		switch op.(type) {
+		case *Reveal, Reveal, *VecReveal, VecReveal, *BoolReveal, BoolReveal:
+			wires = append(wires, outputWires(op)...)
+		}
+	}
+	return wires
+}
+
+// Returns the output wires of the RevealTo gates whose recipient is 'party' (private outputs), in the order of the circuit
+func (c Circuit) PrivateOutputWires(party PartyID) []WireID {
+	var wires []WireID
+	for _, op := range c {
+		if ro, isPrivate := op.(privateOpeningOperation); isPrivate && ro.Recipient() == party {
+			wires = append(wires, op.Output())
+		}
+	}
+	return wires
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+)
+
+// Version of the circuit file format, increased on every incompatible change
+const CircuitFormatVersion = 2
+
+// Constructors of the operations that can be serialized, indexed by the name used in the "type" field
+var operationTypes = map[string]func() Operation{
+	"Input":        func() Operation { return &Input{} },
+	"Add":          func() Operation { return &Add{} },
+	"AddCst":       func() Operation { return &AddCst{} },
+	"Sub":          func() Operation { return &Sub{} },
+	"Mult":         func() Operation { return &Mult{} },
+	"MultCst":      func() Operation { return &MultCst{} },
+	"Reveal":       func() Operation { return &Reveal{} },
+	"RevealTo":     func() Operation { return &RevealTo{} },
+	"LessThan":     func() Operation { return &LessThan{} },
+	"Equal":        func() Operation { return &Equal{} },
+	"BitDecompose": func() Operation { return &BitDecompose{} },
+	"MultFixed":    func() Operation { return &MultFixed{} },
+	"Inverse":      func() Operation { return &Inverse{} },
+	"Div":          func() Operation { return &Div{} },
+	"PowCst":       func() Operation { return &PowCst{} },
+	"Select":       func() Operation { return &Select{} },
+
+	"VecInput":   func() Operation { return &VecInput{} },
+	"VecAdd":     func() Operation { return &VecAdd{} },
+	"VecAddCst":  func() Operation { return &VecAddCst{} },
+	"VecSub":     func() Operation { return &VecSub{} },
+	"VecMult":    func() Operation { return &VecMult{} },
+	"VecMultCst": func() Operation { return &VecMultCst{} },
+	"VecReveal":  func() Operation { return &VecReveal{} },
+
+	"DotProduct":    func() Operation { return &DotProduct{} },
+	"DotProductCst": func() Operation { return &DotProductCst{} },
+	"MatMul":        func() Operation { return &MatMul{} },
+
+	"BoolInput":  func() Operation { return &BoolInput{} },
+	"Xor":        func() Operation { return &Xor{} },
+	"Not":        func() Operation { return &Not{} },
+	"And":        func() Operation { return &And{} },
+	"BoolReveal": func() Operation { return &BoolReveal{} },
+}
+
+// Returns the name under which the operation is serialized
+func operationType(op Operation) (string, error) {
+	name := reflect.Indirect(reflect.ValueOf(op)).Type().Name()
+	if _, known := operationTypes[name]; !known {
+		return "", fmt.Errorf("operation of type %T cannot be serialized", op)
+	}
+	return name, nil
+}
+
+// Serialize each operation as a JSON object holding its fields and its type, e.g. {"type":"Add","In1":0,"In2":1,"Out":2}
+func (c Circuit) MarshalJSON() ([]byte, error) {
+	ops := make([]json.RawMessage, len(c))
+	for i, op := range c {
+		name, err := operationType(op)
+		if err != nil {
+			return nil, err
+		}
+		fields, err := json.Marshal(op)
+		if err != nil {
+			return nil, err
+		}
+
+		var buf bytes.Buffer
+		buf.WriteString(fmt.Sprintf(`{"type":%q`, name))
+		if len(fields) > 2 {
+			buf.WriteByte(',')
+		}
+		buf.Write(fields[1:])
+		ops[i] = buf.Bytes()
+	}
+	return json.Marshal(ops)
+}
+
+// Deserialize a circuit produced by MarshalJSON. Operations are decoded as pointers, like in test_circuits.go
+func (c *Circuit) UnmarshalJSON(data []byte) error {
+	var ops []json.RawMessage
+	if err := json.Unmarshal(data, &ops); err != nil {
+		return err
+	}
+
+	circuit := make(Circuit, len(ops))
+	for i, raw := range ops {
+		var header struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(raw, &header); err != nil {
+			return fmt.Errorf("operation %d: %s", i, err)
+		}
+		newOp, known := operationTypes[header.Type]
+		if !known {
+			return fmt.Errorf("operation %d: unknown operation type %q", i, header.Type)
+		}
+		op := newOp()
+		if err := json.Unmarshal(raw, op); err != nil {
+			return fmt.Errorf("operation %d: %s", i, err)
+		}
+		circuit[i] = op
+	}
+
+	*c = circuit
+	return nil
+}
+
+// On-disk representation of a test circuit
+type circuitFile struct {
+	Version int `json:"version"`
+	TestCircuit
+}
+
+// Read a circuit (with its peers and inputs) from a JSON file
+func ReadCircuitFile(path string) (*TestCircuit, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file circuitFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	if file.Version != CircuitFormatVersion {
+		return nil, fmt.Errorf("%s: unsupported circuit format version %d (expected %d)", path, file.Version, CircuitFormatVersion)
+	}
+
+	return &file.TestCircuit, nil
+}
+
+// Write a circuit (with its peers and inputs) to a JSON file
+func WriteCircuitFile(path string, testCircuit *TestCircuit) error {
+	data, err := json.MarshalIndent(circuitFile{Version: CircuitFormatVersion, TestCircuit: *testCircuit}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
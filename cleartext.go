@@ -0,0 +1,59 @@
+package main
+
+import (
+	"math/big"
+)
+
+// State of the evaluation of a circuit in clear: it computes the same values as Protocol.Run, modulo Params.T,
+// but without secret sharing nor networking. Used as a reference to check the result of the MPC protocol
+type ClearEvaluation struct {
+	Inputs         map[PartyID]map[GateID]uint64 // input of each party for each of its Input gates
+	Wires          map[WireID]*big.Int           // value of each wire, in [0, q[
+	Outputs        map[WireID]uint64             // value revealed by each Reveal gate
+	PrivateOutputs map[WireID]uint64             // value revealed by each RevealTo gate, to its recipient
+	err            error                         // first error raised by an operation, which stops the evaluation
+}
+
+// Evaluate the circuit in clear with the given inputs. The circuit is validated beforehand, the parties being the
+// ones providing inputs and the recipients of the private openings. The evaluation stops at the first operation that
+// fails, e.g. with a ZeroInverseError
+func EvaluateCircuit(circuit Circuit, inputs map[PartyID]map[GateID]uint64) (*ClearEvaluation, error) {
+	peers := make(map[PartyID]string, len(inputs))
+	for id := range inputs {
+		peers[id] = ""
+	}
+	for _, op := range circuit {
+		if po, isPrivate := op.(privateOpeningOperation); isPrivate {
+			peers[po.Recipient()] = ""
+		}
+	}
+	if err := ValidateCircuit(circuit, peers, nil); err != nil {
+		return nil, err
+	}
+	for id := range inputs {
+		if err := ValidateInputs(circuit, id, inputs[id]); err != nil {
+			return nil, err
+		}
+	}
+
+	ce := &ClearEvaluation{
+		Inputs:         inputs,
+		Wires:          make(map[WireID]*big.Int, len(circuit)),
+		Outputs:        make(map[WireID]uint64),
+		PrivateOutputs: make(map[WireID]uint64),
+	}
+	for _, op := range circuit {
+		op.EvalClear(ce)
+		if ce.err != nil {
+			return nil, ce.err
+		}
+	}
+	return ce, nil
+}
+
+// Record an error raised by an operation, such as a ZeroInverseError
+func (ce *ClearEvaluation) fail(err error) {
+	if ce.err == nil {
+		ce.err = err
+	}
+}
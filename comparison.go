@@ -0,0 +1,366 @@
+package main
+
+import (
+	"math/big"
+)
+
+// Maximal value of the inputs of a LessThan gate: (q-1)/2, i.e. 15-bit values for q = 65537
+var MaxComparable = new(big.Int).Rsh(q, 1).Uint64()
+
+// Shared bit [In1 < In2], for inputs in [0, MaxComparable]. Since q is odd, In1 < In2 if and only if
+// 2(In1-In2) mod q is odd, when |In1-In2| < q/2. The least significant bit of z = 2(In1-In2) is computed with m
+// preprocessed random bits r_i of a uniform r < q, where m is the bit length of q: c = z + r is opened, and
+// LSB(z) = c_0 XOR r_0 XOR [c < r]. The comparison of the public c with the bits of r is a product of m affine
+// functions of the bits, reduced in a tree of log2(m) rounds of Beaver multiplications, then XORed in a last round.
+// The gate takes 2+log2(m) rounds, m random bits and about 2m triplets, keyed by its internal wires
+type LessThan struct {
+	In1 WireID
+	In2 WireID
+	Out WireID
+}
+
+// Number of random bits of a LessThan gate: the bit length of q
+var comparisonBits = q.BitLen()
+
+// Internal wires of a LessThan gate: a_i at Out+1+i and b_i at Out+1+m+i, such that [c < r] restricted to the bits
+// 0 to i is a_i + b_i*[c < r] restricted to the bits 0 to i-1, then c_0 XOR r_0 at Out+1+2m. The random bit r_i
+// and the triplets are keyed by the wires from Out+1
+func (lto LessThan) a(i int) WireID {
+	return lto.Out + 1 + WireID(i)
+}
+
+func (lto LessThan) b(i int) WireID {
+	return lto.Out + 1 + WireID(comparisonBits+i)
+}
+
+func (lto LessThan) u() WireID {
+	return lto.Out + 1 + WireID(2*comparisonBits)
+}
+
+func (lto LessThan) InternalWires() int {
+	return 2*comparisonBits + 1
+}
+
+// Pairs of adjacent groups of bits (low, high) combined at each level of the reduction tree. A group is identified
+// by its lowest bit, where its a and b are stored. The b of the group of bit 0 is never needed
+func comparisonLevels() [][][2]int {
+	groups := make([]int, comparisonBits)
+	for i := range groups {
+		groups[i] = i
+	}
+	var levels [][][2]int
+	for len(groups) > 1 {
+		var pairs [][2]int
+		var next []int
+		for i := 0; i < len(groups); i += 2 {
+			if i+1 < len(groups) {
+				pairs = append(pairs, [2]int{groups[i], groups[i+1]})
+			}
+			next = append(next, groups[i])
+		}
+		levels = append(levels, pairs)
+		groups = next
+	}
+	return levels
+}
+
+var lessThanLevels = comparisonLevels()
+
+// Number of multiplications of a pair: a_low = a_high + b_high*a_low, and b_low = b_high*b_low if needed
+func pairMults(pair [2]int) int {
+	if pair[0] == 0 {
+		return 1
+	}
+	return 2
+}
+
+// Returns the index of the first triplet used in the round
+func (lto LessThan) firstTriplet(round int) int {
+	index := 0
+	for _, pairs := range lessThanLevels[:round-1] {
+		for _, pair := range pairs {
+			index += pairMults(pair)
+		}
+	}
+	return index
+}
+
+func (lto LessThan) TripletWires() []WireID {
+	return vectorWires(lto.Out+1, lto.firstTriplet(len(lessThanLevels)+1)+1)
+}
+
+func (lto LessThan) RandomBitWires() []WireID {
+	return vectorWires(lto.Out+1, comparisonBits)
+}
+
+func (lto LessThan) FieldBits() bool {
+	return true
+}
+
+func (lto LessThan) IsMult() bool {
+	return true
+}
+
+func (lto LessThan) Output() WireID {
+	return lto.Out
+}
+
+func (lto LessThan) Inputs() []WireID {
+	return []WireID{lto.In1, lto.In2}
+}
+
+func (lto LessThan) Remap(f func(WireID) WireID) Operation {
+	return &LessThan{In1: f(lto.In1), In2: f(lto.In2), Out: f(lto.Out)}
+}
+
+func (lto LessThan) Eval(cep *Protocol) {
+	evalRounds(cep, lto)
+}
+
+func (lto LessThan) EvalClear(ce *ClearEvaluation) {
+	z := new(big.Int).Sub(ce.Wires[lto.In1], ce.Wires[lto.In2])
+	z.Lsh(z, 1).Mod(z, q)
+	ce.Wires[lto.Out] = big.NewInt(int64(z.Bit(0)))
+}
+
+// Round 0 opens c, the rounds 1 to log2(m) reduce the comparison [c < r], and the last round XORs it with c_0 XOR r_0
+func (lto LessThan) OpenRounds() int {
+	return len(lessThanLevels) + 2
+}
+
+func (lto LessThan) RoundOpenCount(round int) int {
+	switch {
+	case round == 0:
+		return 1
+	case round <= len(lessThanLevels):
+		return 2 * (lto.firstTriplet(round+1) - lto.firstTriplet(round))
+	default:
+		return 2
+	}
+}
+
+func (lto LessThan) RoundShares(cep *Protocol, round int) []*big.Int {
+	switch {
+	case round == 0:
+		z := new(big.Int).Sub(cep.WireOutput[lto.In1], cep.WireOutput[lto.In2])
+		return []*big.Int{maskShare(cep, z.Lsh(z, 1), lto.RandomBitWires())}
+	case round <= len(lessThanLevels):
+		var shares []*big.Int
+		triplet := lto.firstTriplet(round)
+		for _, pair := range lessThanLevels[round-1] {
+			low, high := pair[0], pair[1]
+			bHigh := cep.WireOutput[lto.b(high)]
+			shares = append(shares, beaverShares(bHigh, cep.WireOutput[lto.a(low)], cep.BeaverTriplets[lto.Out+1+WireID(triplet)])...)
+			if pairMults(pair) == 2 {
+				shares = append(shares, beaverShares(bHigh, cep.WireOutput[lto.b(low)], cep.BeaverTriplets[lto.Out+2+WireID(triplet)])...)
+			}
+			triplet += pairMults(pair)
+		}
+		return shares
+	default:
+		triplet := cep.BeaverTriplets[lto.Out+1+WireID(lto.firstTriplet(round))]
+		return beaverShares(cep.WireOutput[lto.u()], cep.WireOutput[lto.a(0)], triplet)
+	}
+}
+
+func (lto LessThan) RoundOpen(cep *Protocol, round int, opened []*big.Int) {
+	switch {
+	case round == 0:
+		// With c public, r_i*(1-c_i) and 1-(c_i XOR r_i) are affine in r_i: [c < r] restricted to the bits 0 to i is
+		// r_i if c_i = 0 and r_i = 1, 0 if c_i = 1 and r_i = 0, and the comparison of the bits 0 to i-1 otherwise
+		c := opened[0]
+		for i, w := range lto.RandomBitWires() {
+			r := cep.RandomBits[w]
+			notR := new(big.Int).Sub(constantShare(cep, 1), r)
+			if c.Bit(i) == 0 {
+				cep.WireOutput[lto.a(i)] = new(big.Int).Set(r)
+				cep.WireOutput[lto.b(i)] = notR
+			} else {
+				cep.WireOutput[lto.a(i)] = big.NewInt(0)
+				cep.WireOutput[lto.b(i)] = new(big.Int).Set(r)
+			}
+			if i == 0 && c.Bit(0) == 0 {
+				cep.WireOutput[lto.u()] = new(big.Int).Set(r)
+			} else if i == 0 {
+				cep.WireOutput[lto.u()] = notR
+			}
+		}
+	case round <= len(lessThanLevels):
+		triplet := lto.firstTriplet(round)
+		for _, pair := range lessThanLevels[round-1] {
+			low, high := pair[0], pair[1]
+			aHigh, bHigh := cep.WireOutput[lto.a(high)], cep.WireOutput[lto.b(high)]
+			aLow, bLow := cep.WireOutput[lto.a(low)], cep.WireOutput[lto.b(low)]
+			product := beaverProduct(cep, bHigh, aLow, cep.BeaverTriplets[lto.Out+1+WireID(triplet)], opened[:2])
+			product.Add(product, aHigh)
+			cep.WireOutput[lto.a(low)] = product.Mod(product, q)
+			if pairMults(pair) == 2 {
+				cep.WireOutput[lto.b(low)] = beaverProduct(cep, bHigh, bLow, cep.BeaverTriplets[lto.Out+2+WireID(triplet)], opened[2:4])
+			}
+			opened = opened[2*pairMults(pair):]
+			triplet += pairMults(pair)
+		}
+	default:
+		// u XOR lt = u + lt - 2*u*lt
+		u, lt := cep.WireOutput[lto.u()], cep.WireOutput[lto.a(0)]
+		triplet := cep.BeaverTriplets[lto.Out+1+WireID(lto.firstTriplet(round))]
+		product := beaverProduct(cep, u, lt, triplet, opened)
+		z := new(big.Int).Add(u, lt)
+		z.Sub(z, product.Lsh(product, 1))
+		cep.WireOutput[lto.Out] = z.Mod(z, q)
+	}
+}
+
+func (lto LessThan) BeaverTriplet(count int) []BeaverTriplet {
+	return Mult{}.BeaverTriplet(count)
+}
+
+// Shared bit [In1 = In2], for any inputs. With r a uniform r < q whose m bits r_i are preprocessed, c = In1-In2+r
+// mod q is opened: In1 = In2 if and only if c = r, i.e. if all the bits c_i XOR r_i are 0. The product of the m
+// shared bits 1 - (c_i XOR r_i), affine in r_i since c is public, is reduced in a tree of log2(m) rounds of Beaver
+// multiplications. The gate takes 1+log2(m) rounds, m random bits and m-1 triplets, keyed by its internal wires
+type Equal struct {
+	In1 WireID
+	In2 WireID
+	Out WireID
+}
+
+// Internal wires of an Equal gate: the product of the bits of the group starting at bit i is stored at Out+1+i, in
+// the same reduction tree as the LessThan gates. The random bit r_i is keyed by Out+1+i and the triplets by the wires
+// from Out+1
+func (eo Equal) p(i int) WireID {
+	return eo.Out + 1 + WireID(i)
+}
+
+func (eo Equal) InternalWires() int {
+	return comparisonBits
+}
+
+// Returns the index of the first triplet used in the round: one per pair of groups of the previous levels
+func (eo Equal) firstTriplet(round int) int {
+	index := 0
+	for _, pairs := range lessThanLevels[:round-1] {
+		index += len(pairs)
+	}
+	return index
+}
+
+func (eo Equal) TripletWires() []WireID {
+	return vectorWires(eo.Out+1, comparisonBits-1)
+}
+
+func (eo Equal) RandomBitWires() []WireID {
+	return vectorWires(eo.Out+1, comparisonBits)
+}
+
+func (eo Equal) FieldBits() bool {
+	return true
+}
+
+func (eo Equal) IsMult() bool {
+	return true
+}
+
+func (eo Equal) Output() WireID {
+	return eo.Out
+}
+
+func (eo Equal) Inputs() []WireID {
+	return []WireID{eo.In1, eo.In2}
+}
+
+func (eo Equal) Remap(f func(WireID) WireID) Operation {
+	return &Equal{In1: f(eo.In1), In2: f(eo.In2), Out: f(eo.Out)}
+}
+
+func (eo Equal) Eval(cep *Protocol) {
+	evalRounds(cep, eo)
+}
+
+func (eo Equal) EvalClear(ce *ClearEvaluation) {
+	d := new(big.Int).Sub(ce.Wires[eo.In1], ce.Wires[eo.In2])
+	ce.Wires[eo.Out] = big.NewInt(0)
+	if d.Mod(d, q).Sign() == 0 {
+		ce.Wires[eo.Out] = big.NewInt(1)
+	}
+}
+
+// Round 0 opens c, the rounds 1 to log2(m) reduce the product of the bits
+func (eo Equal) OpenRounds() int {
+	return len(lessThanLevels) + 1
+}
+
+func (eo Equal) RoundOpenCount(round int) int {
+	if round == 0 {
+		return 1
+	}
+	return 2 * len(lessThanLevels[round-1])
+}
+
+func (eo Equal) RoundShares(cep *Protocol, round int) []*big.Int {
+	if round == 0 {
+		d := new(big.Int).Sub(cep.WireOutput[eo.In1], cep.WireOutput[eo.In2])
+		return []*big.Int{maskShare(cep, d, eo.RandomBitWires())}
+	}
+	var shares []*big.Int
+	for i, pair := range lessThanLevels[round-1] {
+		triplet := cep.BeaverTriplets[eo.Out+1+WireID(eo.firstTriplet(round)+i)]
+		shares = append(shares, beaverShares(cep.WireOutput[eo.p(pair[0])], cep.WireOutput[eo.p(pair[1])], triplet)...)
+	}
+	return shares
+}
+
+func (eo Equal) RoundOpen(cep *Protocol, round int, opened []*big.Int) {
+	if round == 0 {
+		// 1 - (c_i XOR r_i) is r_i if c_i = 1, and 1 - r_i otherwise
+		c := opened[0]
+		for i, w := range eo.RandomBitWires() {
+			r := cep.RandomBits[w]
+			if c.Bit(i) == 1 {
+				cep.WireOutput[eo.p(i)] = new(big.Int).Set(r)
+			} else {
+				cep.WireOutput[eo.p(i)] = new(big.Int).Sub(constantShare(cep, 1), r)
+			}
+		}
+		return
+	}
+	for i, pair := range lessThanLevels[round-1] {
+		triplet := cep.BeaverTriplets[eo.Out+1+WireID(eo.firstTriplet(round)+i)]
+		low, high := cep.WireOutput[eo.p(pair[0])], cep.WireOutput[eo.p(pair[1])]
+		cep.WireOutput[eo.p(pair[0])] = beaverProduct(cep, low, high, triplet, opened[2*i:2*i+2])
+	}
+	if round == len(lessThanLevels) {
+		cep.WireOutput[eo.Out] = new(big.Int).Set(cep.WireOutput[eo.p(0)])
+	}
+}
+
+func (eo Equal) BeaverTriplet(count int) []BeaverTriplet {
+	return Mult{}.BeaverTriplet(count)
+}
+
+// Shared bit [x < y], for x and y in [0, MaxComparable]
+func (x Wire) LessThan(y Wire) Wire {
+	x.b.check(y)
+	return x.b.emit(func(out WireID) Operation { return &LessThan{In1: x.ID, In2: y.ID, Out: out} })
+}
+
+// Shared bit [x > y], for x and y in [0, MaxComparable]
+func (x Wire) GreaterThan(y Wire) Wire {
+	return y.LessThan(x)
+}
+
+// Minimum of x and y, in [0, MaxComparable]: y + [x < y]*(x-y)
+func (x Wire) Min(y Wire) Wire {
+	return y.Add(x.LessThan(y).Mul(x.Sub(y)))
+}
+
+// Maximum of x and y, in [0, MaxComparable]: x + [x < y]*(y-x)
+func (x Wire) Max(y Wire) Wire {
+	return x.Add(x.LessThan(y).Mul(y.Sub(x)))
+}
+
+// Shared bit [x = y]
+func (x Wire) Equal(y Wire) Wire {
+	x.b.check(y)
+	return x.b.emit(func(out WireID) Operation { return &Equal{In1: x.ID, In2: y.ID, Out: out} })
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// Fill color of the nodes in the DOT export, depending on the kind of operation
+const (
+	dotColorInput  = "lightblue"
+	dotColorLocal  = "white"
+	dotColorMult   = "salmon"
+	dotColorReveal = "palegreen"
+)
+
+// Write the circuit as a Graphviz DOT graph: one node per operation, annotated with its type, parameters (party,
+// constant, ...), output wire and multiplicative depth, and one edge per wire read by an operation. Input gates,
+// local gates, multiplications and reveals have distinct colors
+func WriteDOT(w io.Writer, circuit Circuit) error {
+	var sb strings.Builder
+	sb.WriteString("digraph circuit {\n")
+	sb.WriteString("\trankdir=TB;\n")
+	sb.WriteString("\tnode [shape=box, style=filled];\n")
+
+	depths := MultiplicativeDepths(circuit)
+	producer := make(map[WireID]int, len(circuit))
+	for i, op := range circuit {
+		for _, w := range outputWires(op) {
+			producer[w] = i
+		}
+
+		name, err := operationType(op)
+		if err != nil {
+			return err
+		}
+		out := fmt.Sprintf("wire %d", op.Output())
+		if vo, isVector := op.(vectorOperation); isVector {
+			out = fmt.Sprintf("wires %d..%d", op.Output(), op.Output()+WireID(vo.Length())-1)
+		}
+		label := fmt.Sprintf("%s%s\\nout: %s\\ndepth: %d", name, operationParameters(op), out, depths[i])
+		sb.WriteString(fmt.Sprintf("\top%d [label=\"%s\", fillcolor=%s];\n", i, label, dotColor(op)))
+	}
+
+	for i, op := range circuit {
+		// A vector operation reading the elements of a vector gets a single edge from its producer
+		vo, isVector := op.(vectorOperation)
+		drawn := make(map[int]bool)
+		for _, in := range op.Inputs() {
+			if from, exists := producer[in]; exists && isVector {
+				if !drawn[from] {
+					drawn[from] = true
+					sb.WriteString(fmt.Sprintf("\top%d -> op%d [label=\"w%d..w%d\"];\n", from, i, in, in+WireID(vo.Length())-1))
+				}
+			} else if exists {
+				sb.WriteString(fmt.Sprintf("\top%d -> op%d [label=\"w%d\"];\n", from, i, in))
+			} else {
+				sb.WriteString(fmt.Sprintf("\tundefined%d [label=\"undefined\\nwire %d\", shape=ellipse, fillcolor=red];\n", in, in))
+				sb.WriteString(fmt.Sprintf("\tundefined%d -> op%d [label=\"w%d\"];\n", in, i, in))
+			}
+		}
+	}
+
+	sb.WriteString("}\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// Returns the parameters of the operation other than its wires, e.g. " (Party: 2)"
+func operationParameters(op Operation) string {
+	v := reflect.Indirect(reflect.ValueOf(op))
+	var params []string
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.Type == reflect.TypeOf(WireID(0)) || field.Type == reflect.TypeOf([]WireID(nil)) {
+			continue
+		}
+		params = append(params, fmt.Sprintf("%s: %v", field.Name, v.Field(i).Interface()))
+	}
+	if len(params) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (%s)", strings.Join(params, ", "))
+}
+
+func dotColor(op Operation) string {
+	if _, isInput := op.(inputOperation); isInput {
+		return dotColorInput
+	}
+	if len(Circuit{op}.OutputWires()) > 0 {
+		return dotColorReveal
+	}
+	if _, isPrivate := op.(privateOpeningOperation); isPrivate {
+		return dotColorReveal
+	}
+	if op.IsMult() {
+		return dotColorMult
+	}
+	return dotColorLocal
+}
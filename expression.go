@@ -0,0 +1,346 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"unicode"
+)
+
+// Error reported when an expression cannot be parsed. Pos is the 1-based position of the offending character
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at position %d: %s", e.Pos, e.Msg)
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenIdent
+	tokenOperator
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	pos   int
+	value uint64
+}
+
+// Split the expression into numbers, identifiers and single character operators
+func tokenize(expr string) ([]token, error) {
+	var tokens []token
+	runes := []rune(expr)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r):
+			start := i
+			for i < len(runes) && unicode.IsDigit(runes[i]) {
+				i++
+			}
+			value, err := strconv.ParseUint(string(runes[start:i]), 10, 64)
+			if err != nil {
+				return nil, &SyntaxError{start + 1, fmt.Sprintf("invalid number %q", string(runes[start:i]))}
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: string(runes[start:i]), pos: start + 1, value: value})
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: string(runes[start:i]), pos: start + 1})
+		case r == '+' || r == '-' || r == '*' || r == '^' || r == '(' || r == ')':
+			tokens = append(tokens, token{kind: tokenOperator, text: string(r), pos: i + 1})
+			i++
+		default:
+			return nil, &SyntaxError{i + 1, fmt.Sprintf("unexpected character %q", r)}
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(runes) + 1}), nil
+}
+
+// Value of a sub-expression: either a public constant or a secret-shared wire
+type exprValue struct {
+	secret bool
+	wire   WireID
+	cst    *big.Int
+}
+
+// Recursive descent compiler emitting the operations of the circuit while parsing
+type exprCompiler struct {
+	tokens  []token
+	next    int
+	parties map[string]PartyID
+	inputs  map[string]WireID
+	circuit Circuit
+	wire    WireID
+	fixed   *FixedPoint // encoding of the inputs and of the result, nil for integers
+}
+
+// Compile an arithmetic expression such as "6 + 6*(x+y-z) + 3*(x+y-z)^2" into a circuit revealing its value.
+// Each variable is the input of the party it is mapped to in 'parties'. The input of party i is carried by wire i,
+// the other wires being allocated afterwards. Constants are handled with AddCst and MultCst, Mult being only used
+// when both operands are secret. All the computations are done modulo Params.T
+func CompileExpression(expr string, parties map[string]PartyID) (Circuit, error) {
+	return compileExpression(expr, parties, nil)
+}
+
+// Compile an arithmetic expression on fixed-point numbers encoded with fp, like CompileExpression. The integer
+// constants are encoded when they are added, and the products of two secret values are computed with MultFixed
+func CompileFixedExpression(expr string, parties map[string]PartyID, fp FixedPoint) (Circuit, error) {
+	return compileExpression(expr, parties, &fp)
+}
+
+func compileExpression(expr string, parties map[string]PartyID, fixed *FixedPoint) (Circuit, error) {
+	c := &exprCompiler{parties: parties, inputs: make(map[string]WireID), fixed: fixed}
+
+	var err error
+	if c.tokens, err = tokenize(expr); err != nil {
+		return nil, err
+	}
+
+	// Input gates come first, ordered by party
+	names := make([]string, 0, len(parties))
+	owners := make(map[PartyID]string, len(parties))
+	for name, party := range parties {
+		if other, taken := owners[party]; taken {
+			return nil, fmt.Errorf("party %d cannot provide both %q and %q", party, name, other)
+		}
+		owners[party] = name
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return parties[names[i]] < parties[names[j]] })
+	for _, name := range names {
+		party := parties[name]
+		c.inputs[name] = WireID(party)
+		c.circuit = append(c.circuit, &Input{Party: party, Out: WireID(party)})
+		if WireID(party) >= c.wire {
+			c.wire = WireID(party) + 1
+		}
+	}
+
+	res, err := c.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := c.peek(); tok.kind != tokenEOF {
+		return nil, &SyntaxError{tok.pos, fmt.Sprintf("unexpected %q", tok.text)}
+	}
+	if !res.secret {
+		return nil, fmt.Errorf("expression does not depend on any input")
+	}
+
+	c.circuit = append(c.circuit, &Reveal{In: res.wire, Out: c.newWire()})
+	return c.circuit, nil
+}
+
+func (c *exprCompiler) peek() token {
+	return c.tokens[c.next]
+}
+
+func (c *exprCompiler) accept(op string) bool {
+	if tok := c.peek(); tok.kind == tokenOperator && tok.text == op {
+		c.next++
+		return true
+	}
+	return false
+}
+
+func (c *exprCompiler) newWire() WireID {
+	c.wire++
+	return c.wire - 1
+}
+
+// expr := term (('+' | '-') term)*
+func (c *exprCompiler) parseExpr() (exprValue, error) {
+	lhs, err := c.parseTerm()
+	if err != nil {
+		return lhs, err
+	}
+	for {
+		var sub bool
+		if c.accept("+") {
+			sub = false
+		} else if c.accept("-") {
+			sub = true
+		} else {
+			return lhs, nil
+		}
+		rhs, err := c.parseTerm()
+		if err != nil {
+			return rhs, err
+		}
+		if sub {
+			lhs = c.sub(lhs, rhs)
+		} else {
+			lhs = c.add(lhs, rhs)
+		}
+	}
+}
+
+// term := unary ('*' unary)*
+func (c *exprCompiler) parseTerm() (exprValue, error) {
+	lhs, err := c.parseUnary()
+	if err != nil {
+		return lhs, err
+	}
+	for c.accept("*") {
+		rhs, err := c.parseUnary()
+		if err != nil {
+			return rhs, err
+		}
+		lhs = c.mul(lhs, rhs)
+	}
+	return lhs, nil
+}
+
+// unary := '-' unary | power
+func (c *exprCompiler) parseUnary() (exprValue, error) {
+	if c.accept("-") {
+		val, err := c.parseUnary()
+		if err != nil {
+			return val, err
+		}
+		return c.mul(val, exprValue{cst: new(big.Int).Sub(q, big.NewInt(1))}), nil
+	}
+	return c.parsePower()
+}
+
+// power := primary ('^' number)?
+func (c *exprCompiler) parsePower() (exprValue, error) {
+	base, err := c.parsePrimary()
+	if err != nil {
+		return base, err
+	}
+	if !c.accept("^") {
+		return base, nil
+	}
+	tok := c.peek()
+	if tok.kind != tokenNumber {
+		return base, &SyntaxError{tok.pos, "exponent must be a non-negative integer"}
+	}
+	c.next++
+	return c.pow(base, tok.value), nil
+}
+
+// primary := number | identifier | '(' expr ')'
+func (c *exprCompiler) parsePrimary() (exprValue, error) {
+	tok := c.peek()
+	switch {
+	case tok.kind == tokenNumber:
+		c.next++
+		return exprValue{cst: new(big.Int).Mod(new(big.Int).SetUint64(tok.value), q)}, nil
+	case tok.kind == tokenIdent:
+		c.next++
+		wire, known := c.inputs[tok.text]
+		if !known {
+			return exprValue{}, &SyntaxError{tok.pos, fmt.Sprintf("unknown variable %q", tok.text)}
+		}
+		return exprValue{secret: true, wire: wire}, nil
+	case c.accept("("):
+		val, err := c.parseExpr()
+		if err != nil {
+			return val, err
+		}
+		if !c.accept(")") {
+			return val, &SyntaxError{c.peek().pos, "missing closing parenthesis"}
+		}
+		return val, nil
+	case tok.kind == tokenEOF:
+		return exprValue{}, &SyntaxError{tok.pos, "unexpected end of expression"}
+	default:
+		return exprValue{}, &SyntaxError{tok.pos, fmt.Sprintf("unexpected %q", tok.text)}
+	}
+}
+
+func (c *exprCompiler) add(x, y exprValue) exprValue {
+	switch {
+	case !x.secret && !y.secret:
+		return exprValue{cst: new(big.Int).Mod(new(big.Int).Add(x.cst, y.cst), q)}
+	case !x.secret:
+		return c.addCst(y, x.cst)
+	case !y.secret:
+		return c.addCst(x, y.cst)
+	}
+	out := c.newWire()
+	c.circuit = append(c.circuit, &Add{In1: x.wire, In2: y.wire, Out: out})
+	return exprValue{secret: true, wire: out}
+}
+
+func (c *exprCompiler) sub(x, y exprValue) exprValue {
+	if x.secret && y.secret {
+		out := c.newWire()
+		c.circuit = append(c.circuit, &Sub{In1: x.wire, In2: y.wire, Out: out})
+		return exprValue{secret: true, wire: out}
+	}
+	return c.add(x, c.mul(y, exprValue{cst: new(big.Int).Sub(q, big.NewInt(1))}))
+}
+
+func (c *exprCompiler) mul(x, y exprValue) exprValue {
+	switch {
+	case !x.secret && !y.secret:
+		return exprValue{cst: new(big.Int).Mod(new(big.Int).Mul(x.cst, y.cst), q)}
+	case !x.secret:
+		return c.multCst(y, x.cst)
+	case !y.secret:
+		return c.multCst(x, y.cst)
+	}
+	out := c.newWire()
+	var op Operation = &Mult{In1: x.wire, In2: y.wire, Out: out}
+	if c.fixed != nil {
+		op = &MultFixed{In1: x.wire, In2: y.wire, Out: out, FracBits: c.fixed.FracBits}
+		c.wire = out + WireID(len(outputWires(op)))
+	}
+	c.circuit = append(c.circuit, op)
+	return exprValue{secret: true, wire: out}
+}
+
+// Square-and-multiply exponentiation
+func (c *exprCompiler) pow(x exprValue, exp uint64) exprValue {
+	if !x.secret {
+		return exprValue{cst: new(big.Int).Exp(x.cst, new(big.Int).SetUint64(exp), q)}
+	}
+
+	res := exprValue{cst: big.NewInt(1)}
+	for ; exp > 0; exp >>= 1 {
+		if exp&1 == 1 {
+			res = c.mul(res, x)
+		}
+		if exp > 1 {
+			x = c.mul(x, x)
+		}
+	}
+	return res
+}
+
+func (c *exprCompiler) addCst(x exprValue, cst *big.Int) exprValue {
+	if cst.Sign() == 0 {
+		return x
+	}
+	if c.fixed != nil {
+		cst = new(big.Int).Lsh(cst, c.fixed.FracBits)
+		cst.Mod(cst, q)
+	}
+	out := c.newWire()
+	c.circuit = append(c.circuit, &AddCst{In: x.wire, CstValue: cst.Uint64(), Out: out})
+	return exprValue{secret: true, wire: out}
+}
+
+func (c *exprCompiler) multCst(x exprValue, cst *big.Int) exprValue {
+	if cst.Cmp(big.NewInt(1)) == 0 {
+		return x
+	}
+	out := c.newWire()
+	c.circuit = append(c.circuit, &MultCst{In: x.wire, CstValue: cst.Uint64(), Out: out})
+	return exprValue{secret: true, wire: out}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// Fixed-point encoding of real numbers with FracBits fractional bits: x is encoded as round(x*2^FracBits) mod q, so
+// that negative numbers are encoded above MaxComparable. Sums and products by integer constants are computed with the
+// usual gates, while the products of two encoded numbers must be truncated by FracBits bits with MultFixed
+type FixedPoint struct {
+	FracBits uint
+}
+
+// Encode the number, which must be representable, i.e. |round(x*2^FracBits)| <= MaxComparable
+func (fp FixedPoint) Encode(x float64) (uint64, error) {
+	scaled := math.Round(math.Ldexp(x, int(fp.FracBits)))
+	if math.IsNaN(scaled) || math.Abs(scaled) > float64(MaxComparable) {
+		return 0, fmt.Errorf("%g cannot be encoded with %d fractional bits modulo %d", x, fp.FracBits, Params.T)
+	}
+	if scaled < 0 {
+		return Params.T - uint64(-scaled), nil
+	}
+	return uint64(scaled), nil
+}
+
+// Decode the number encoded by the value modulo q
+func (fp FixedPoint) Decode(value uint64) float64 {
+	value %= Params.T
+	if value > MaxComparable {
+		return math.Ldexp(-float64(Params.T-value), -int(fp.FracBits))
+	}
+	return math.Ldexp(float64(value), -int(fp.FracBits))
+}
+
+// Bound on the products computed by MultFixed: before the truncation, the product of the encoded numbers must lie in
+// [-2^(FixedPointBits-1), 2^(FixedPointBits-1)). It is masked by a random value of truncationMaskBits bits, so that
+// the masked value never wraps around q. Because of the small plaintext modulus, the mask only hides the product
+// statistically with truncationMaskBits-FixedPointBits = 5 bits, instead of the usual 40 or more
+const FixedPointBits = 10
+
+const truncationMaskBits = 15
+
+// Error raised by the evaluation in clear of a MultFixed gate whose product before the truncation lies outside of
+// [-2^(FixedPointBits-1), 2^(FixedPointBits-1)). The protocol cannot detect it without revealing the product: the
+// masked value may then wrap around q, which gives a wrong output
+type FixedPointRangeError struct {
+	Op      Operation // faulty MultFixed gate
+	Product int64     // product of the encoded numbers, before the truncation
+}
+
+func (e *FixedPointRangeError) Error() string {
+	return fmt.Sprintf("operation %T of wire %d multiplies to %d, outside of [-%d, %d)", e.Op, e.Op.Output(), e.Product, 1<<(FixedPointBits-1), 1<<(FixedPointBits-1))
+}
+
+// Product of two fixed-point numbers with FracBits fractional bits, in [-2^(FixedPointBits-1-2*FracBits),
+// 2^(FixedPointBits-1-2*FracBits)) once decoded. The product x of the encoded numbers is computed with a Beaver
+// triplet, then truncated by FracBits bits with the probabilistic truncation of Catrina and Saxena: c = x +
+// 2^(FixedPointBits-1) + r is opened, where r is a random value whose bits are preprocessed, and the output is
+// (x - (c mod 2^FracBits - r mod 2^FracBits)) / 2^FracBits. It is floor(x/2^FracBits), plus 1 with a probability
+// of (x mod 2^FracBits)/2^FracBits, so that the error is below 2^-FracBits once decoded. The gate takes 2 rounds,
+// one triplet keyed by Out and truncationMaskBits random bits keyed by its internal wires
+type MultFixed struct {
+	In1      WireID
+	In2      WireID
+	Out      WireID
+	FracBits uint
+}
+
+// The product before the truncation is stored at Out+1, and the random bits are keyed by Out+1 to
+// Out+truncationMaskBits
+func (mfo MultFixed) InternalWires() int {
+	return truncationMaskBits
+}
+
+func (mfo MultFixed) TripletWires() []WireID {
+	return []WireID{mfo.Out}
+}
+
+func (mfo MultFixed) RandomBitWires() []WireID {
+	return vectorWires(mfo.Out+1, truncationMaskBits)
+}
+
+func (mfo MultFixed) FieldBits() bool {
+	return false
+}
+
+func (mfo MultFixed) Validate() *ValidationError {
+	if mfo.FracBits >= FixedPointBits {
+		return &ValidationError{Kind: InvalidFracBits, Value: uint64(mfo.FracBits)}
+	}
+	return nil
+}
+
+func (mfo MultFixed) IsMult() bool {
+	return true
+}
+
+func (mfo MultFixed) Output() WireID {
+	return mfo.Out
+}
+
+func (mfo MultFixed) Inputs() []WireID {
+	return []WireID{mfo.In1, mfo.In2}
+}
+
+func (mfo MultFixed) Remap(f func(WireID) WireID) Operation {
+	return &MultFixed{In1: f(mfo.In1), In2: f(mfo.In2), Out: f(mfo.Out), FracBits: mfo.FracBits}
+}
+
+func (mfo MultFixed) Eval(cep *Protocol) {
+	evalRounds(cep, mfo)
+}
+
+// Evaluated in clear, the product is always rounded down, and the evaluation fails with a FixedPointRangeError if
+// the product is out of range
+func (mfo MultFixed) EvalClear(ce *ClearEvaluation) {
+	x := new(big.Int).Mul(signed(ce.Wires[mfo.In1]), signed(ce.Wires[mfo.In2]))
+	if bound := int64(1) << (FixedPointBits - 1); x.Int64() < -bound || x.Int64() >= bound {
+		ce.fail(&FixedPointRangeError{Op: mfo, Product: x.Int64()})
+	}
+	x.Div(x, new(big.Int).Lsh(big.NewInt(1), mfo.FracBits))
+	ce.Wires[mfo.Out] = x.Mod(x, q)
+}
+
+// Returns the signed integer encoded by the value modulo q, in [-MaxComparable, MaxComparable]
+func signed(value *big.Int) *big.Int {
+	x := new(big.Int).Mod(value, q)
+	if x.Uint64() > MaxComparable {
+		x.Sub(x, q)
+	}
+	return x
+}
+
+// Round 0 multiplies the inputs, round 1 opens the masked product
+func (mfo MultFixed) OpenRounds() int {
+	return 2
+}
+
+func (mfo MultFixed) RoundOpenCount(round int) int {
+	if round == 0 {
+		return 2
+	}
+	return 1
+}
+
+func (mfo MultFixed) RoundShares(cep *Protocol, round int) []*big.Int {
+	if round == 0 {
+		return beaverShares(cep.WireOutput[mfo.In1], cep.WireOutput[mfo.In2], cep.BeaverTriplets[mfo.Out])
+	}
+	x := new(big.Int).Add(cep.WireOutput[mfo.Out+1], constantShare(cep, 1<<(FixedPointBits-1)))
+	return []*big.Int{maskShare(cep, x, mfo.RandomBitWires())}
+}
+
+func (mfo MultFixed) RoundOpen(cep *Protocol, round int, opened []*big.Int) {
+	if round == 0 {
+		cep.WireOutput[mfo.Out+1] = beaverProduct(cep, cep.WireOutput[mfo.In1], cep.WireOutput[mfo.In2], cep.BeaverTriplets[mfo.Out], opened)
+		return
+	}
+
+	// x - (c mod 2^FracBits) + (r mod 2^FracBits), divided by 2^FracBits
+	low := int64(opened[0].Uint64() & (1<<mfo.FracBits - 1))
+	z := new(big.Int).Sub(cep.WireOutput[mfo.Out+1], constantShare(cep, low))
+	for i, w := range mfo.RandomBitWires()[:mfo.FracBits] {
+		z.Add(z, new(big.Int).Lsh(cep.RandomBits[w], uint(i)))
+	}
+	z.Mul(z, new(big.Int).ModInverse(new(big.Int).Lsh(big.NewInt(1), mfo.FracBits), q))
+	cep.WireOutput[mfo.Out] = z.Mod(z, q)
+}
+
+func (mfo MultFixed) BeaverTriplet(count int) []BeaverTriplet {
+	return Mult{}.BeaverTriplet(count)
+}
+
+// Product of the fixed-point numbers x and y encoded with fp
+func (x Wire) MulFixed(y Wire, fp FixedPoint) Wire {
+	x.b.check(y)
+	return x.b.emit(func(out WireID) Operation {
+		return &MultFixed{In1: x.ID, In2: y.ID, Out: out, FracBits: fp.FracBits}
+	})
+}
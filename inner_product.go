@@ -0,0 +1,161 @@
+package main
+
+import (
+	"math/big"
+)
+
+// Inner product of the wires In1 and In2, which must have the same length n. Each product In1[i]*In2[i] uses its own
+// Beaver triplet, keyed by Out+i, but the masked values of all the elements are opened in a single round and the
+// products are summed up locally, so that the gate costs one multiplication round whatever n
+type DotProduct struct {
+	In1 []WireID
+	In2 []WireID
+	Out WireID
+}
+
+func (dpo DotProduct) Validate() *ValidationError {
+	if len(dpo.In1) != len(dpo.In2) {
+		return &ValidationError{Kind: MismatchedLength}
+	}
+	if len(dpo.In1) == 0 {
+		return &ValidationError{Kind: EmptyOperands}
+	}
+	return nil
+}
+
+// The internal wires only key the triplets of the elements after the first one
+func (dpo DotProduct) InternalWires() int {
+	return len(dpo.In1) - 1
+}
+
+func (dpo DotProduct) IsMult() bool {
+	return true
+}
+
+func (dpo DotProduct) Output() WireID {
+	return dpo.Out
+}
+
+func (dpo DotProduct) Inputs() []WireID {
+	return append(append([]WireID(nil), dpo.In1...), dpo.In2...)
+}
+
+func (dpo DotProduct) Remap(f func(WireID) WireID) Operation {
+	return &DotProduct{In1: remapWires(dpo.In1, f), In2: remapWires(dpo.In2, f), Out: f(dpo.Out)}
+}
+
+// Returns the wires mapped by f
+func remapWires(wires []WireID, f func(WireID) WireID) []WireID {
+	remapped := make([]WireID, len(wires))
+	for i, w := range wires {
+		remapped[i] = f(w)
+	}
+	return remapped
+}
+
+func (dpo DotProduct) Eval(cep *Protocol) {
+	evalOpening(cep, dpo)
+}
+
+func (dpo DotProduct) EvalClear(ce *ClearEvaluation) {
+	z := big.NewInt(0)
+	for i := range dpo.In1 {
+		z.Add(z, new(big.Int).Mul(ce.Wires[dpo.In1[i]], ce.Wires[dpo.In2[i]]))
+	}
+	ce.Wires[dpo.Out] = z.Mod(z, q)
+}
+
+// Returns our shares of x_i-a_i and y_i-b_i for each element i
+func (dpo DotProduct) Shares(cep *Protocol) []*big.Int {
+	shares := make([]*big.Int, 0, 2*len(dpo.In1))
+	for i := range dpo.In1 {
+		shares = append(shares, beaverShares(cep.WireOutput[dpo.In1[i]], cep.WireOutput[dpo.In2[i]], cep.BeaverTriplets[dpo.Out+WireID(i)])...)
+	}
+	return shares
+}
+
+// Computes our share of the sum of the x_i*y_i
+func (dpo DotProduct) Open(cep *Protocol, opened []*big.Int) {
+	z := big.NewInt(0)
+	for i := range dpo.In1 {
+		z.Add(z, beaverProduct(cep, cep.WireOutput[dpo.In1[i]], cep.WireOutput[dpo.In2[i]], cep.BeaverTriplets[dpo.Out+WireID(i)], opened[2*i:2*i+2]))
+	}
+	cep.WireOutput[dpo.Out] = z.Mod(z, q)
+}
+
+func (dpo DotProduct) OpenCount() int {
+	return 2 * len(dpo.In1)
+}
+
+// Returns the shares of one triplet: the dealer calls it once per element (see tripletWires)
+func (dpo DotProduct) BeaverTriplet(count int) []BeaverTriplet {
+	return Mult{}.BeaverTriplet(count)
+}
+
+// Inner product of the wires In with the public weights CstValues, of the same length, computed locally
+type DotProductCst struct {
+	In        []WireID
+	CstValues []uint64
+	Out       WireID
+}
+
+func (dpco DotProductCst) Validate() *ValidationError {
+	if len(dpco.In) != len(dpco.CstValues) {
+		return &ValidationError{Kind: MismatchedLength}
+	}
+	return nil
+}
+
+func (dpco DotProductCst) IsMult() bool {
+	return false
+}
+
+func (dpco DotProductCst) Output() WireID {
+	return dpco.Out
+}
+
+func (dpco DotProductCst) Inputs() []WireID {
+	return append([]WireID(nil), dpco.In...)
+}
+
+func (dpco DotProductCst) Remap(f func(WireID) WireID) Operation {
+	return &DotProductCst{In: remapWires(dpco.In, f), CstValues: append([]uint64(nil), dpco.CstValues...), Out: f(dpco.Out)}
+}
+
+func (dpco DotProductCst) Eval(cep *Protocol) {
+	z := big.NewInt(0)
+	for i, w := range dpco.In {
+		z.Add(z, new(big.Int).Mul(cep.WireOutput[w], new(big.Int).SetUint64(dpco.CstValues[i])))
+	}
+	cep.WireOutput[dpco.Out] = z.Mod(z, q)
+}
+
+func (dpco DotProductCst) EvalClear(ce *ClearEvaluation) {
+	z := big.NewInt(0)
+	for i, w := range dpco.In {
+		z.Add(z, new(big.Int).Mul(ce.Wires[w], new(big.Int).SetUint64(dpco.CstValues[i])))
+	}
+	ce.Wires[dpco.Out] = z.Mod(z, q)
+}
+
+func (dpco DotProductCst) BeaverTriplet(count int) []BeaverTriplet {
+	return nil
+}
+
+// Inner product of the vectors x and y
+func (x Vector) Dot(y Vector) Wire {
+	x.checkLen(y)
+	return x.b.emit(func(out WireID) Operation {
+		return &DotProduct{In1: vectorWires(x.ID, x.Len), In2: vectorWires(y.ID, y.Len), Out: out}
+	})
+}
+
+// Inner product of the vector x with the public weights, one per element
+func (x Vector) DotConst(weights []uint64) Wire {
+	if len(weights) != x.Len {
+		panic("vectors of different lengths")
+	}
+	return x.b.emit(func(out WireID) Operation {
+		return &DotProductCst{In: vectorWires(x.ID, x.Len), CstValues: append([]uint64(nil), weights...), Out: out}
+	})
+}
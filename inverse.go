@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// Error raised when the value opened to compute an Inverse or Div gate is zero, which stops the evaluation of the
+// circuit. It happens when the inverted value is zero, or with a probability of 1/q when the random mask is zero
+type ZeroInverseError struct {
+	Op Operation // faulty Inverse or Div gate
+}
+
+func (e *ZeroInverseError) Error() string {
+	return fmt.Sprintf("operation %T of wire %d cannot invert zero", e.Op, e.Op.Output())
+}
+
+// Inverse of In modulo q, with the masked-inverse trick: the first value r of a Beaver triplet (r, b, c), uniform
+// and unknown to all the parties, is multiplied with In by opening In-b only, since r*In = r*(In-b) + c. Then m =
+// r*In is opened, which hides In as long as it is not zero, and the output is m^-1 * r. The gate takes 2 rounds and
+// the triplet keyed by Out, whose share of m is also stored at Out between both rounds
+type Inverse struct {
+	In  WireID
+	Out WireID
+}
+
+// Our share of r*x, where r is the first value of the triplet and e = x-b is the opened masked value
+func maskedProduct(triplet BeaverTriplet, e *big.Int) *big.Int {
+	z := new(big.Int).Mul(e, triplet.a)
+	z.Add(z, triplet.c)
+	return z.Mod(z, q)
+}
+
+// Share of the mask r, and our share of In-b to open in round 0
+func (io Inverse) mask(cep *Protocol) (BeaverTriplet, *big.Int) {
+	triplet := cep.BeaverTriplets[io.Out]
+	return triplet, new(big.Int).Sub(cep.WireOutput[io.In], triplet.b)
+}
+
+func (io Inverse) IsMult() bool {
+	return true
+}
+
+func (io Inverse) Output() WireID {
+	return io.Out
+}
+
+func (io Inverse) Inputs() []WireID {
+	return []WireID{io.In}
+}
+
+func (io Inverse) Remap(f func(WireID) WireID) Operation {
+	return &Inverse{In: f(io.In), Out: f(io.Out)}
+}
+
+func (io Inverse) Eval(cep *Protocol) {
+	evalRounds(cep, io)
+}
+
+func (io Inverse) EvalClear(ce *ClearEvaluation) {
+	ce.Wires[io.Out] = ce.inverse(io, ce.Wires[io.In])
+}
+
+// Round 0 multiplies In by the mask, round 1 opens the masked value
+func (io Inverse) OpenRounds() int {
+	return 2
+}
+
+func (io Inverse) RoundOpenCount(round int) int {
+	return 1
+}
+
+func (io Inverse) RoundShares(cep *Protocol, round int) []*big.Int {
+	if round == 0 {
+		_, e := io.mask(cep)
+		return []*big.Int{e}
+	}
+	return []*big.Int{cep.WireOutput[io.Out]}
+}
+
+func (io Inverse) RoundOpen(cep *Protocol, round int, opened []*big.Int) {
+	triplet := cep.BeaverTriplets[io.Out]
+	if round == 0 {
+		cep.WireOutput[io.Out] = maskedProduct(triplet, opened[0])
+		return
+	}
+	cep.WireOutput[io.Out] = cep.unmaskInverse(io, opened[0], triplet.a)
+}
+
+func (io Inverse) BeaverTriplet(count int) []BeaverTriplet {
+	return Mult{}.BeaverTriplet(count)
+}
+
+// Quotient In1/In2 modulo q, i.e. In1 * In2^-1, computed like an Inverse gate without the extra multiplication:
+// r*In1 is computed in round 0 alongside r*In2, with a second triplet keyed by Out+1, so that the output m^-1 *
+// r*In1 is available after 2 rounds. The shares of r*In2 and r*In1 are stored at Out and Out+1 between both rounds
+type Div struct {
+	In1 WireID
+	In2 WireID
+	Out WireID
+}
+
+// The internal wire holds r*In1 and keys the second triplet
+func (do Div) InternalWires() int {
+	return 1
+}
+
+func (do Div) IsMult() bool {
+	return true
+}
+
+func (do Div) Output() WireID {
+	return do.Out
+}
+
+func (do Div) Inputs() []WireID {
+	return []WireID{do.In1, do.In2}
+}
+
+func (do Div) Remap(f func(WireID) WireID) Operation {
+	return &Div{In1: f(do.In1), In2: f(do.In2), Out: f(do.Out)}
+}
+
+func (do Div) Eval(cep *Protocol) {
+	evalRounds(cep, do)
+}
+
+func (do Div) EvalClear(ce *ClearEvaluation) {
+	z := new(big.Int).Mul(ce.Wires[do.In1], ce.inverse(do, ce.Wires[do.In2]))
+	ce.Wires[do.Out] = z.Mod(z, q)
+}
+
+// Round 0 multiplies both inputs by the mask, round 1 opens the masked divisor
+func (do Div) OpenRounds() int {
+	return 2
+}
+
+func (do Div) RoundOpenCount(round int) int {
+	if round == 0 {
+		return 3
+	}
+	return 1
+}
+
+func (do Div) RoundShares(cep *Protocol, round int) []*big.Int {
+	if round == 0 {
+		triplet, e := Inverse{In: do.In2, Out: do.Out}.mask(cep)
+		return append([]*big.Int{e}, beaverShares(triplet.a, cep.WireOutput[do.In1], cep.BeaverTriplets[do.Out+1])...)
+	}
+	return []*big.Int{cep.WireOutput[do.Out]}
+}
+
+func (do Div) RoundOpen(cep *Protocol, round int, opened []*big.Int) {
+	triplet := cep.BeaverTriplets[do.Out]
+	if round == 0 {
+		cep.WireOutput[do.Out] = maskedProduct(triplet, opened[0])
+		cep.WireOutput[do.Out+1] = beaverProduct(cep, triplet.a, cep.WireOutput[do.In1], cep.BeaverTriplets[do.Out+1], opened[1:])
+		return
+	}
+	cep.WireOutput[do.Out] = cep.unmaskInverse(do, opened[0], cep.WireOutput[do.Out+1])
+}
+
+func (do Div) BeaverTriplet(count int) []BeaverTriplet {
+	return Mult{}.BeaverTriplet(count)
+}
+
+// Returns m^-1 * share, or fails the evaluation and returns 0 if the opened masked value m is zero
+func (cep *Protocol) unmaskInverse(op Operation, m, share *big.Int) *big.Int {
+	if m.Sign() == 0 {
+		cep.fail(&ZeroInverseError{Op: op})
+		return big.NewInt(0)
+	}
+	z := new(big.Int).Mul(share, new(big.Int).ModInverse(m, q))
+	return z.Mod(z, q)
+}
+
+// Returns the inverse of the value modulo q, or fails the evaluation and returns 0 if it is zero
+func (ce *ClearEvaluation) inverse(op Operation, value *big.Int) *big.Int {
+	x := new(big.Int).Mod(value, q)
+	if x.Sign() == 0 {
+		ce.fail(&ZeroInverseError{Op: op})
+		return x
+	}
+	return x.ModInverse(x, q)
+}
+
+// Inverse of x modulo q. The evaluation fails with a ZeroInverseError if x is zero
+func (x Wire) Inverse() Wire {
+	return x.b.emit(func(out WireID) Operation { return &Inverse{In: x.ID, Out: out} })
+}
+
+// Quotient x/y modulo q, exact when y divides x. The evaluation fails with a ZeroInverseError if y is zero
+func (x Wire) Div(y Wire) Wire {
+	x.b.check(y)
+	return x.b.emit(func(out WireID) Operation { return &Div{In1: x.ID, In2: y.ID, Out: out} })
+}
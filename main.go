@@ -1,43 +1,72 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"github.com/ldsec/lattigo/ring"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
 
+// Usage: mpc [flags] to evaluate a circuit, mpc inspect [flags] to describe it without evaluating it, or mpc stats
+// [flags] to estimate its cost
 func main() {
-	var circuitID int
-	var testCircuit *TestCircuit
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "inspect":
+			inspect(os.Args[2:])
+			return
+		case "stats":
+			stats(os.Args[2:])
+			return
+		}
+	}
+
+	var circuitFlags circuitFlags
 	var centralized bool
 
-	flag.IntVar(&circuitID, "id", 1, fmt.Sprintf("ID between 1 and %d of the template circuit", len(TestCircuits)))
+	circuitFlags.register(flag.CommandLine)
 	flag.BoolVar(&centralized, "c", false, "Use a centralized generation of beaver triplets")
 
 	flag.Parse()
 
-	if circuitID <= 0 || circuitID > len(TestCircuits) {
-		panic(fmt.Sprintf("Invalid argument: ID must be between 1 and %d", len(TestCircuits)))
-	}
+	testCircuit, err := circuitFlags.load()
+	check(err)
 
-	testCircuit = TestCircuits[circuitID-1]
+	check(ValidateCircuit(testCircuit.Circuit, testCircuit.Peers, nil))
+	for partyID := range testCircuit.Peers {
+		check(ValidateInputs(testCircuit.Circuit, partyID, testCircuit.Inputs[partyID]))
+	}
 
 	beaverTriplets := make(map[PartyID]map[WireID]BeaverTriplet)
+	randomBits := make(map[PartyID]map[WireID]*big.Int)
+	matrixTriplets := make(map[PartyID]map[WireID]MatrixTriplet)
+	binaryTriplets := make(map[PartyID]map[WireID]BinaryTriplet)
 
 	for peerID := range testCircuit.Peers {
 		beaverTriplets[peerID] = make(map[WireID]BeaverTriplet)
+		randomBits[peerID] = make(map[WireID]*big.Int)
+		matrixTriplets[peerID] = make(map[WireID]MatrixTriplet)
+		binaryTriplets[peerID] = make(map[WireID]BinaryTriplet)
+	}
+
+	// There is no generation of binary triplets with OT yet, and LocalBinaryDealer would reveal the inputs of the And
+	// gates: only the trusted dealer can provide them
+	if !centralized && hasBinaryTriplets(testCircuit.Circuit) {
+		check(errors.New("the circuit has And gates, whose binary triplets can only be generated by the dealer: use -c"))
 	}
 
 	if centralized {
-		for _, op := range testCircuit.Circuit {
-			if triplets := op.BeaverTriplet(len(testCircuit.Peers)); triplets != nil {
-				for id, triplet := range triplets {
-					beaverTriplets[PartyID(id)][op.Output()] = triplet
-				}
-			}
-		}
+		beaverTriplets = DealBeaverTriplets(testCircuit.Circuit, len(testCircuit.Peers))
+		randomBits = DealRandomBits(testCircuit.Circuit, len(testCircuit.Peers))
+		matrixTriplets = DealMatrixTriplets(testCircuit.Circuit, len(testCircuit.Peers))
+		binaryTriplets = DealBinaryTriplets(testCircuit.Circuit, len(testCircuit.Peers))
 	}
 
 	wg := new(sync.WaitGroup)
@@ -48,7 +77,7 @@ func main() {
 
 			defer wg.Done()
 
-			partyInput := testCircuit.Inputs[id][GateID(id)]
+			partyInputs := testCircuit.Inputs[id]
 			// Create a local party
 			lp, err := NewLocalParty(id, testCircuit.Peers)
 			check(err)
@@ -67,38 +96,306 @@ func main() {
 			if !centralized {
 				beaverProtocol := lp.NewBeaverProtocol(Params)
 				ComputeBeaverTripletHE(beaverProtocol, beaverTriplets, testCircuit.Circuit)
+				ComputeRandomBitsHE(beaverProtocol, randomBits, testCircuit.Circuit)
+				ComputeMatrixTripletsHE(beaverProtocol, matrixTriplets, testCircuit.Circuit)
 			}
 
 			// Create a new circuit evaluation protocol
-			protocol := lp.NewProtocol(partyInput, testCircuit.Circuit, beaverTriplets[id])
+			protocol := lp.NewProtocol(partyInputs, testCircuit.Circuit, beaverTriplets[id])
+			protocol.RandomBits = randomBits[id]
+			protocol.MatrixTriplets = matrixTriplets[id]
+			protocol.BinaryTriplets = binaryTriplets[id]
 
 			// Evaluate the circuit
-			protocol.Run()
+			check(protocol.Run())
 
-			// Print output
-			fmt.Println(fmt.Sprintf("Peer %d ended computation with output %d.", protocol.ID, protocol.Output))
+			// Print outputs
+			outputs := make([]string, 0, len(protocol.Outputs))
+			for _, wire := range testCircuit.Circuit.OutputWires() {
+				outputs = append(outputs, fmt.Sprintf("wire %d = %s", wire, circuitFlags.format(protocol.Outputs[wire])))
+			}
+			for _, wire := range testCircuit.Circuit.PrivateOutputWires(protocol.ID) {
+				outputs = append(outputs, fmt.Sprintf("wire %d = %s (private)", wire, circuitFlags.format(protocol.PrivateOutputs[wire])))
+			}
+			fmt.Println(fmt.Sprintf("Peer %d ended computation with outputs %s.", protocol.ID, strings.Join(outputs, ", ")))
 		}(partyID)
 	}
 	wg.Wait()
 }
 
+// Describe the circuit without evaluating it: list its operations, or print it as a Graphviz DOT graph with -dot
+func inspect(args []string) {
+	var circuitFlags circuitFlags
+	var dot bool
+
+	fs := flag.NewFlagSet("inspect", flag.ExitOnError)
+	circuitFlags.register(fs)
+	fs.BoolVar(&dot, "dot", false, "Print the circuit as a Graphviz DOT graph")
+
+	check(fs.Parse(args))
+
+	testCircuit, err := circuitFlags.load()
+	check(err)
+
+	if dot {
+		check(WriteDOT(os.Stdout, testCircuit.Circuit))
+		return
+	}
+
+	for i, op := range testCircuit.Circuit {
+		name, err := operationType(op)
+		check(err)
+		fmt.Printf("%d: %s%s %v -> %d\n", i, name, operationParameters(op), op.Inputs(), op.Output())
+	}
+	if err := ValidateCircuit(testCircuit.Circuit, testCircuit.Peers, nil); err != nil {
+		fmt.Println(err)
+	}
+}
+
+// Print the estimated cost of the circuit as JSON
+func stats(args []string) {
+	var circuitFlags circuitFlags
+	var parties int
+
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	circuitFlags.register(fs)
+	fs.IntVar(&parties, "parties", 0, "Number of parties to estimate the cost for (default: number of peers of the circuit)")
+
+	check(fs.Parse(args))
+
+	testCircuit, err := circuitFlags.load()
+	check(err)
+
+	if parties <= 0 {
+		parties = len(testCircuit.Peers)
+	}
+
+	circuitStats, err := ComputeStats(testCircuit.Circuit, parties)
+	check(err)
+
+	data, err := json.MarshalIndent(circuitStats, "", "  ")
+	check(err)
+	fmt.Println(string(data))
+}
+
+// Flags selecting the circuit to use, shared by all the commands
+type circuitFlags struct {
+	id       int
+	path     string
+	expr     string
+	inputs   string
+	optimize bool
+	frac     uint
+}
+
+func (cf *circuitFlags) register(fs *flag.FlagSet) {
+	fs.IntVar(&cf.id, "id", 1, fmt.Sprintf("ID between 1 and %d of the template circuit", len(TestCircuits)))
+	fs.StringVar(&cf.path, "circuit", "", "Path of a JSON circuit file to evaluate instead of a template circuit")
+	fs.StringVar(&cf.expr, "expr", "", "Arithmetic expression to evaluate instead of a template circuit, e.g. \"6 + 6*(x+y-z) + 3*(x+y-z)^2\"")
+	fs.StringVar(&cf.inputs, "inputs", "", "Inputs of the expression given with -expr, e.g. \"x=9,y=5,z=7\". The i-th variable is the input of party i")
+	fs.BoolVar(&cf.optimize, "optimize", false, "Optimize the circuit before using it")
+	fs.UintVar(&cf.frac, "frac", 0, "Number of fractional bits of fixed-point numbers: the inputs given with -inputs are encoded, the products of -expr use MultFixed and the outputs are decoded. Only with -expr, 0 for integers")
+}
+
+// Format an output value, decoded as a fixed-point number with -frac
+func (cf *circuitFlags) format(value uint64) string {
+	if cf.frac == 0 {
+		return strconv.FormatUint(value, 10)
+	}
+	return strconv.FormatFloat(FixedPoint{cf.frac}.Decode(value), 'g', -1, 64)
+}
+
+// Returns the circuit selected by the flags: an expression, a circuit file or a template circuit, optimized with
+// -optimize. Only expressions are compiled and checked for fixed-point numbers, so -frac requires -expr
+func (cf *circuitFlags) load() (*TestCircuit, error) {
+	var testCircuit *TestCircuit
+	var err error
+	switch {
+	case cf.frac > 0 && cf.expr == "":
+		err = errors.New("invalid argument: -frac only applies to an expression given with -expr")
+	case cf.expr != "":
+		testCircuit, err = expressionCircuit(cf.expr, cf.inputs, cf.frac)
+	case cf.path != "":
+		testCircuit, err = ReadCircuitFile(cf.path)
+	case cf.id <= 0 || cf.id > len(TestCircuits):
+		err = fmt.Errorf("invalid argument: ID must be between 1 and %d", len(TestCircuits))
+	default:
+		testCircuit = TestCircuits[cf.id-1]
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if cf.optimize {
+		if err := ValidateCircuit(testCircuit.Circuit, testCircuit.Peers, nil); err != nil {
+			return nil, err
+		}
+		optimized := *testCircuit
+		optimized.Circuit = Optimize(testCircuit.Circuit)
+		testCircuit = &optimized
+	}
+	return testCircuit, nil
+}
+
 // Use Beaver triplet generation protocol to generate our triplets
 func ComputeBeaverTripletHE(beaverProtocol *BeaverProtocol, beaverTriplets map[PartyID]map[WireID]BeaverTriplet, circuit Circuit) {
 
 	var currIndex uint64 = 0
 	var triplet Triplets
 	for _, op := range circuit {
-		if op.IsMult() {
+		for _, w := range tripletWires(op) {
 			if currIndex%(1<<Params.LogN) == 0 {
 				beaverProtocol.Run()
 				triplet = beaverProtocol.BeaverTriplets
 				currIndex = 0
 			}
-			beaverTriplets[beaverProtocol.ID][op.Output()] = BeaverTriplet{
+			beaverTriplets[beaverProtocol.ID][w] = BeaverTriplet{
 				a: ring.NewUint(triplet.ai[currIndex]),
 				b: ring.NewUint(triplet.bi[currIndex]),
 				c: ring.NewUint(triplet.ci[currIndex]),
 			}
+			currIndex++
+		}
+	}
+}
+
+// Use the random bit generation protocol to generate our random bits, consuming Beaver triplets generated with BFV
+func ComputeRandomBitsHE(beaverProtocol *BeaverProtocol, randomBits map[PartyID]map[WireID]*big.Int, circuit Circuit) {
+
+	var currIndex uint64 = 1 << Params.LogN
+	var triplet Triplets
+	nextTriplet := func() BeaverTriplet {
+		if currIndex == 1<<Params.LogN {
+			beaverProtocol.Run()
+			triplet = beaverProtocol.BeaverTriplets
+			currIndex = 0
+		}
+		currIndex++
+		return BeaverTriplet{
+			a: ring.NewUint(triplet.ai[currIndex-1]),
+			b: ring.NewUint(triplet.bi[currIndex-1]),
+			c: ring.NewUint(triplet.ci[currIndex-1]),
+		}
+	}
+
+	for w, bit := range beaverProtocol.NewRandomBitProtocol(nextTriplet).Generate(circuit) {
+		randomBits[beaverProtocol.ID][w] = bit
+	}
+}
+
+// Use the Beaver triplet generation protocol to generate our shares of the matrix triplets of the circuit, laid out
+// in the BFV slots as described by matrixTripletSlots: we choose our shares of A and B, and sum up our shares of the
+// slot-wise products into our share of C = AB
+func ComputeMatrixTripletsHE(beaverProtocol *BeaverProtocol, matrixTriplets map[PartyID]map[WireID]MatrixTriplet, circuit Circuit) {
+
+	ops, slots := matrixTripletSlots(circuit)
+	triplets := make([]MatrixTriplet, len(ops))
+	ai := make([]uint64, 0, slots)
+	bi := make([]uint64, 0, slots)
+	for o, op := range ops {
+		n, k, m := op.dimensions()
+		a := newRandomVec(uint64(n*k), Params.T)
+		b := newRandomVec(uint64(k*m), Params.T)
+		for i := 0; i < n; i++ {
+			for j := 0; j < m; j++ {
+				for l := 0; l < k; l++ {
+					ai = append(ai, a[i*k+l])
+					bi = append(bi, b[l*m+j])
+				}
+			}
+		}
+		triplets[o] = MatrixTriplet{a: make([]*big.Int, n*k), b: make([]*big.Int, k*m), c: make([]*big.Int, n*m)}
+		for i, value := range a {
+			triplets[o].a[i] = ring.NewUint(value)
+		}
+		for i, value := range b {
+			triplets[o].b[i] = ring.NewUint(value)
+		}
+	}
+
+	// Runs of the protocol on at most 1<<Params.LogN slots, the last one being padded with zeros
+	ci := make([]uint64, 0, slots)
+	for start := 0; start < slots; start += 1 << Params.LogN {
+		end := start + 1<<Params.LogN
+		if end > slots {
+			end = slots
+		}
+		a := make([]uint64, 1<<Params.LogN)
+		b := make([]uint64, 1<<Params.LogN)
+		copy(a, ai[start:end])
+		copy(b, bi[start:end])
+		beaverProtocol.RunWithShares(a, b)
+		ci = append(ci, beaverProtocol.BeaverTriplets.ci[:end-start]...)
+	}
+
+	for o, op := range ops {
+		_, k, _ := op.dimensions()
+		for i := range triplets[o].c {
+			sum := big.NewInt(0)
+			for _, value := range ci[:k] {
+				sum.Add(sum, ring.NewUint(value))
+			}
+			triplets[o].c[i] = sum.Mod(sum, q)
+			ci = ci[k:]
+		}
+		matrixTriplets[beaverProtocol.ID][op.Output()] = triplets[o]
+	}
+}
+
+// Build a test circuit from an arithmetic expression, the i-th variable listed in 'inputs' (e.g. "x=9,y=5") being
+// the input of party i, listening on port 6660+i. With frac > 0, the expression is computed on fixed-point numbers
+// with frac fractional bits
+func expressionCircuit(expr string, inputs string, frac uint) (*TestCircuit, error) {
+	testCircuit := &TestCircuit{
+		Peers:  make(map[PartyID]string),
+		Inputs: make(map[PartyID]map[GateID]uint64),
+	}
+	parties := make(map[string]PartyID)
+
+	if strings.TrimSpace(inputs) == "" {
+		return nil, errors.New("no input given: the variables of the expression must be assigned with -inputs, e.g. \"x=9,y=5\"")
+	}
+	for i, assignment := range strings.Split(inputs, ",") {
+		parts := strings.Split(assignment, "=")
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("invalid input %q: expected <variable>=<value>", assignment)
+		}
+		if _, exists := parties[strings.TrimSpace(parts[0])]; exists {
+			return nil, fmt.Errorf("invalid input %q: variable %s is already assigned", assignment, strings.TrimSpace(parts[0]))
+		}
+		value, err := strconv.ParseUint(strings.TrimSpace(parts[1]), 10, 64)
+		if frac > 0 {
+			var number float64
+			if number, err = strconv.ParseFloat(strings.TrimSpace(parts[1]), 64); err == nil {
+				value, err = FixedPoint{frac}.Encode(number)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid input %q: %s", assignment, err)
+		}
+
+		id := PartyID(i)
+		parties[strings.TrimSpace(parts[0])] = id
+		testCircuit.Peers[id] = fmt.Sprintf("localhost:%d", 6660+i)
+		testCircuit.Inputs[id] = map[GateID]uint64{GateID(id): value}
+	}
+
+	var err error
+	if frac > 0 {
+		testCircuit.Circuit, err = CompileFixedExpression(expr, parties, FixedPoint{frac})
+	} else {
+		testCircuit.Circuit, err = CompileExpression(expr, parties)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The protocol cannot detect the products of fixed-point numbers out of range, which give wrong results: they are
+	// checked in clear beforehand
+	if frac > 0 {
+		if _, err := EvaluateCircuit(testCircuit.Circuit, testCircuit.Inputs); err != nil {
+			return nil, err
 		}
 	}
+	return testCircuit, nil
 }
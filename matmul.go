@@ -0,0 +1,229 @@
+package main
+
+import (
+	"github.com/ldsec/lattigo/ring"
+	"math/big"
+)
+
+// Shares of a matrix triplet (A, B, C = AB), for a product of an n×k matrix by a k×m matrix: A is n×k, B is k×m
+// and C is n×m, all stored row by row
+type MatrixTriplet struct {
+	a []*big.Int
+	b []*big.Int
+	c []*big.Int
+}
+
+// Operations consuming a matrix triplet, keyed by their output wire
+type matrixTripletOperation interface {
+	Operation
+	MatrixTriplet(count int) []MatrixTriplet // returns the shares of a new matrix triplet for the parties 0 to count-1
+	dimensions() (n, k, m int)
+}
+
+// Product of the Rows×Inner matrix at In1 by the Inner×Cols matrix at In2, written to the Rows×Cols matrix at Out.
+// The matrices are stored row by row in consecutive wires, like vectors. With a matrix triplet (A, B, C = AB), the
+// masked matrices D = X-A and E = Y-B are opened in one round, and our share of XY is C + X*E + D*Y - D*E, the last
+// term being added by party 0 only. It opens Rows*Inner + Inner*Cols values, instead of Rows*Inner*Cols Mult gates
+type MatMul struct {
+	In1   WireID
+	In2   WireID
+	Out   WireID
+	Rows  int
+	Inner int
+	Cols  int
+}
+
+func (mmo MatMul) dimensions() (n, k, m int) {
+	return mmo.Rows, mmo.Inner, mmo.Cols
+}
+
+func (mmo MatMul) Validate() *ValidationError {
+	if mmo.Rows < 1 || mmo.Inner < 1 || mmo.Cols < 1 {
+		return &ValidationError{Kind: InvalidLength}
+	}
+	return nil
+}
+
+func (mmo MatMul) IsMult() bool {
+	return true
+}
+
+// The product consumes a matrix triplet instead of Beaver triplets
+func (mmo MatMul) TripletWires() []WireID {
+	return nil
+}
+
+func (mmo MatMul) Output() WireID {
+	return mmo.Out
+}
+
+func (mmo MatMul) Inputs() []WireID {
+	return append(vectorWires(mmo.In1, mmo.Rows*mmo.Inner), vectorWires(mmo.In2, mmo.Inner*mmo.Cols)...)
+}
+
+func (mmo MatMul) Length() int {
+	return mmo.Rows * mmo.Cols
+}
+
+func (mmo MatMul) Remap(f func(WireID) WireID) Operation {
+	return &MatMul{In1: f(mmo.In1), In2: f(mmo.In2), Out: f(mmo.Out), Rows: mmo.Rows, Inner: mmo.Inner, Cols: mmo.Cols}
+}
+
+func (mmo MatMul) Eval(cep *Protocol) {
+	evalOpening(cep, mmo)
+}
+
+func (mmo MatMul) EvalClear(ce *ClearEvaluation) {
+	x := make([]*big.Int, mmo.Rows*mmo.Inner)
+	for i := range x {
+		x[i] = ce.Wires[mmo.In1+WireID(i)]
+	}
+	y := make([]*big.Int, mmo.Inner*mmo.Cols)
+	for i := range y {
+		y[i] = ce.Wires[mmo.In2+WireID(i)]
+	}
+	for i, z := range matrixProduct(x, y, mmo.Rows, mmo.Inner, mmo.Cols) {
+		ce.Wires[mmo.Out+WireID(i)] = z
+	}
+}
+
+// Returns our shares of X-A and Y-B
+func (mmo MatMul) Shares(cep *Protocol) []*big.Int {
+	triplet := cep.MatrixTriplets[mmo.Out]
+	shares := make([]*big.Int, 0, mmo.OpenCount())
+	for i, a := range triplet.a {
+		shares = append(shares, new(big.Int).Sub(cep.WireOutput[mmo.In1+WireID(i)], a))
+	}
+	for i, b := range triplet.b {
+		shares = append(shares, new(big.Int).Sub(cep.WireOutput[mmo.In2+WireID(i)], b))
+	}
+	return shares
+}
+
+// Computes our share of XY from the opened D = X-A and E = Y-B
+func (mmo MatMul) Open(cep *Protocol, opened []*big.Int) {
+	triplet := cep.MatrixTriplets[mmo.Out]
+	d, e := opened[:mmo.Rows*mmo.Inner], opened[mmo.Rows*mmo.Inner:]
+	x := make([]*big.Int, len(d))
+	for i := range x {
+		x[i] = cep.WireOutput[mmo.In1+WireID(i)]
+	}
+	y := make([]*big.Int, len(e))
+	for i := range y {
+		y[i] = cep.WireOutput[mmo.In2+WireID(i)]
+	}
+
+	xe := matrixProduct(x, e, mmo.Rows, mmo.Inner, mmo.Cols)
+	dy := matrixProduct(d, y, mmo.Rows, mmo.Inner, mmo.Cols)
+	de := matrixProduct(d, e, mmo.Rows, mmo.Inner, mmo.Cols)
+	for i := range xe {
+		z := new(big.Int).Add(triplet.c[i], xe[i])
+		z.Add(z, dy[i])
+		if cep.ID == 0 {
+			z.Sub(z, de[i])
+		}
+		cep.WireOutput[mmo.Out+WireID(i)] = z.Mod(z, q)
+	}
+}
+
+func (mmo MatMul) OpenCount() int {
+	return mmo.Rows*mmo.Inner + mmo.Inner*mmo.Cols
+}
+
+func (mmo MatMul) BeaverTriplet(count int) []BeaverTriplet {
+	return nil
+}
+
+// Returns the shares of random matrices A and B and of C = AB
+func (mmo MatMul) MatrixTriplet(count int) []MatrixTriplet {
+	a := make([]*big.Int, mmo.Rows*mmo.Inner)
+	for i := range a {
+		a[i] = ring.RandInt(q)
+	}
+	b := make([]*big.Int, mmo.Inner*mmo.Cols)
+	for i := range b {
+		b[i] = ring.RandInt(q)
+	}
+	c := matrixProduct(a, b, mmo.Rows, mmo.Inner, mmo.Cols)
+
+	aShares, bShares, cShares := shareMatrix(a, count), shareMatrix(b, count), shareMatrix(c, count)
+	triplets := make([]MatrixTriplet, count)
+	for i := range triplets {
+		triplets[i] = MatrixTriplet{a: aShares[i], b: bShares[i], c: cShares[i]}
+	}
+	return triplets
+}
+
+// Split each value of the matrix into 'count' additive shares modulo q, and return the matrix of shares of each party
+func shareMatrix(values []*big.Int, count int) [][]*big.Int {
+	shares := make([][]*big.Int, count)
+	for i := range shares {
+		shares[i] = make([]*big.Int, len(values))
+	}
+	for j, value := range values {
+		for i, share := range shareValue(value, count) {
+			shares[i][j] = share
+		}
+	}
+	return shares
+}
+
+// Returns the product modulo q of the n×k matrix x by the k×m matrix y, stored row by row
+func matrixProduct(x, y []*big.Int, n, k, m int) []*big.Int {
+	z := make([]*big.Int, n*m)
+	for i := 0; i < n; i++ {
+		for j := 0; j < m; j++ {
+			sum := big.NewInt(0)
+			for l := 0; l < k; l++ {
+				sum.Add(sum, new(big.Int).Mul(x[i*k+l], y[l*m+j]))
+			}
+			z[i*m+j] = sum.Mod(sum, q)
+		}
+	}
+	return z
+}
+
+// Generate the matrix triplets of all the matrix products of the circuit, for the parties 0 to parties-1
+func DealMatrixTriplets(circuit Circuit, parties int) map[PartyID]map[WireID]MatrixTriplet {
+	matrixTriplets := make(map[PartyID]map[WireID]MatrixTriplet, parties)
+	for id := 0; id < parties; id++ {
+		matrixTriplets[PartyID(id)] = make(map[WireID]MatrixTriplet)
+	}
+	for _, op := range circuit {
+		if mo, isMatrix := op.(matrixTripletOperation); isMatrix {
+			for id, triplet := range mo.MatrixTriplet(parties) {
+				matrixTriplets[PartyID(id)][op.Output()] = triplet
+			}
+		}
+	}
+	return matrixTriplets
+}
+
+// Layout of the matrix triplets of the circuit in the slots of the BFV Beaver triplet protocol: the product of the
+// n×k matrix A by the k×m matrix B takes n*m*k slots, slot (i*m+j)*k+l holding A_il as a and B_lj as b, so that the
+// shares of the c of the slots (i*m+j)*k to (i*m+j+1)*k-1 sum up to a share of C_ij. Returns the operations in the
+// order of their slots, and the total number of slots
+func matrixTripletSlots(circuit Circuit) ([]matrixTripletOperation, int) {
+	var ops []matrixTripletOperation
+	slots := 0
+	for _, op := range circuit {
+		if mo, isMatrix := op.(matrixTripletOperation); isMatrix {
+			n, k, m := mo.dimensions()
+			ops = append(ops, mo)
+			slots += n * m * k
+		}
+	}
+	return ops, slots
+}
+
+// Product of the matrices x (rows×inner) and y (inner×cols), stored row by row in the vectors
+func (x Vector) MatMul(y Vector, rows, cols int) Vector {
+	x.b.checkVector(y)
+	if rows <= 0 || cols <= 0 || x.Len%rows != 0 || x.Len/rows*cols != y.Len {
+		panic("matrices of incompatible dimensions")
+	}
+	inner := x.Len / rows
+	return x.b.emitVector(rows*cols, func(out WireID) Operation {
+		return &MatMul{In1: x.ID, In2: y.ID, Out: out, Rows: rows, Inner: inner, Cols: cols}
+	})
+}
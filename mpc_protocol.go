@@ -14,33 +14,101 @@ type MPCMessage struct {
 	Value uint64
 }
 
+// Structure of network message to carry the shares of all the values opened to a peer during one round
+type BatchMessage struct {
+	Values []uint64
+}
+
 type Protocol struct {
 	*LocalParty
 
-	Input          uint64
-	Output         uint64
+	Inputs         map[GateID]uint64 // our input for each of our Input gates, indexed by the output wire of the gate
+	Outputs        map[WireID]uint64 // value revealed by each Reveal gate, indexed by its output wire
+	PrivateOutputs map[WireID]uint64 // value revealed to us by each RevealTo gate, indexed by its output wire
+	Rounds         uint64            // number of communication rounds done so far
 	Circuit        Circuit
 	WireOutput     map[WireID]*big.Int      // store each the output of each wire
 	BeaverTriplets map[WireID]BeaverTriplet // store the triplet used for each multiplication gate
+	RandomBits     map[WireID]*big.Int      // our share of each preprocessed random bit, keyed like the triplets
+	MatrixTriplets map[WireID]MatrixTriplet // store the matrix triplet used for each matrix product
+	BinaryTriplets map[WireID]BinaryTriplet // store the binary triplet used for each And gate
+	err            error                    // first error raised by an operation, which stops the evaluation
 }
 
-// Create a new protocol to compute the value produced by 'Circuit' when fed with 'input'. The number of beaver triplets given must be >= to the number of multiplication gate present in the circuit
-func (lp *LocalParty) NewProtocol(input uint64, circuit Circuit, beaverTriplets map[WireID]BeaverTriplet) *Protocol {
+// Create a new protocol to compute the value produced by 'Circuit' when fed with 'inputs', which must hold a value for each of our Input gates. The number of beaver triplets given must be >= to the number of multiplication gate present in the circuit
+func (lp *LocalParty) NewProtocol(inputs map[GateID]uint64, circuit Circuit, beaverTriplets map[WireID]BeaverTriplet) *Protocol {
 	cep := new(Protocol)
 	cep.LocalParty = lp
 	cep.WireOutput = make(map[WireID]*big.Int)
+	cep.Outputs = make(map[WireID]uint64)
+	cep.PrivateOutputs = make(map[WireID]uint64)
 	cep.BeaverTriplets = beaverTriplets
 	cep.Circuit = circuit
 
-	cep.Input = input
+	cep.Inputs = inputs
 	return cep
 }
 
-// Start the circuit computation
-func (cep *Protocol) Run() {
+// Start the circuit computation. The circuit is validated beforehand, so that an invalid circuit is reported as a
+// ValidationErrors instead of failing in the middle of the protocol. The circuit is evaluated layer by layer of
+// multiplicative depth, so that all the values opened in a layer are exchanged in a single round. The evaluation
+// stops after the layer in which an operation fails, e.g. with a ZeroInverseError
+func (cep *Protocol) Run() error {
+	if err := cep.validate(); err != nil {
+		return err
+	}
+
+	for _, layer := range Layers(cep.Circuit) {
+		cep.evalLayer(layer)
+		if cep.err != nil {
+			return cep.err
+		}
+	}
+
+	return nil
+}
+
+// Start the circuit computation, evaluating the gates one after the other. Each interactive gate needs its own
+// communication round
+func (cep *Protocol) RunSequential() error {
+	if err := cep.validate(); err != nil {
+		return err
+	}
+
 	for _, op := range cep.Circuit {
 		op.Eval(cep)
+		if cep.err != nil {
+			return cep.err
+		}
 	}
 
-	cep.Output = cep.WireOutput[cep.Circuit[len(cep.Circuit)-1].Output()].Uint64()
+	return nil
+}
+
+// Record an error raised by an operation, such as a ZeroInverseError. Since the parties raise it on the same opened
+// values, they all stop the evaluation after the same layer
+func (cep *Protocol) fail(err error) {
+	if cep.err == nil {
+		cep.err = err
+	}
+}
+
+func (cep *Protocol) validate() error {
+	peers := make(map[PartyID]string, len(cep.Peers))
+	for id, peer := range cep.Peers {
+		peers[id] = peer.Addr
+	}
+	if err := ValidateCircuit(cep.Circuit, peers, cep.BeaverTriplets); err != nil {
+		return err
+	}
+	if err := ValidateRandomBits(cep.Circuit, cep.RandomBits); err != nil {
+		return err
+	}
+	if err := ValidateMatrixTriplets(cep.Circuit, cep.MatrixTriplets); err != nil {
+		return err
+	}
+	if err := ValidateBinaryTriplets(cep.Circuit, cep.BinaryTriplets); err != nil {
+		return err
+	}
+	return ValidateInputs(cep.Circuit, cep.ID, cep.Inputs)
 }
@@ -1,7 +1,16 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"math"
+	"math/big"
+	"math/bits"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"sync"
 	"testing"
 )
@@ -16,9 +25,14 @@ func TestEval(t *testing.T) {
 			beaverProtocol := make([]*BeaverProtocol, N, N)
 
 			beaverTriplets := make(map[PartyID]map[WireID]BeaverTriplet)
+			randomBits := make(map[PartyID]map[WireID]*big.Int)
+			matrixTriplets := make(map[PartyID]map[WireID]MatrixTriplet)
 			for peerID := range testCase.Peers {
 				beaverTriplets[peerID] = make(map[WireID]BeaverTriplet)
+				randomBits[peerID] = make(map[WireID]*big.Int)
+				matrixTriplets[peerID] = make(map[WireID]MatrixTriplet)
 			}
+			binaryDealer := LocalBinaryDealer{Seed: int64(i)}
 
 			var err error
 			wg := new(sync.WaitGroup)
@@ -49,28 +63,33 @@ func TestEval(t *testing.T) {
 				go func(bp *BeaverProtocol, group *sync.WaitGroup, bt map[PartyID]map[WireID]BeaverTriplet) {
 					defer group.Done()
 					ComputeBeaverTripletHE(bp, bt, testCase.Circuit)
+					ComputeRandomBitsHE(bp, randomBits, testCase.Circuit)
+					ComputeMatrixTripletsHE(bp, matrixTriplets, testCase.Circuit)
 				}(p, wg2, beaverTriplets)
 			}
 			wg2.Wait()
 
 			for i, lp := range localParties {
-				protocol[i] = lp.NewProtocol(testCase.Inputs[lp.ID][GateID(i)], testCase.Circuit, beaverTriplets[lp.ID])
+				protocol[i] = lp.NewProtocol(testCase.Inputs[lp.ID], testCase.Circuit, beaverTriplets[lp.ID])
+				protocol[i].RandomBits = randomBits[lp.ID]
+				protocol[i].MatrixTriplets = matrixTriplets[lp.ID]
+				protocol[i].BinaryTriplets = binaryDealer.Triplets(testCase.Circuit, lp.ID, N)
 			}
 
 			for _, p := range protocol {
 				p.Add(1)
 				go func(protocol *Protocol) {
 					defer protocol.Done()
-					protocol.Run()
+					if err := protocol.Run(); err != nil {
+						t.Error(err)
+					}
 				}(p)
 			}
 
 			wg.Wait()
 
 			for _, p := range protocol {
-				if p.Output != testCase.ExpOutput {
-					t.Errorf(p.LocalParty.String(), "result", p.Output, "expected", testCase.ExpOutput)
-				}
+				checkOutputs(t, testCase, p)
 			}
 
 		})
@@ -80,65 +99,1549 @@ func TestEval(t *testing.T) {
 func TestTrustedThirdParty(t *testing.T) {
 	for i, testCase := range TestCircuits {
 		t.Run(fmt.Sprintf("circuit%d", i+1), func(t *testing.T) {
-			N := len(testCase.Peers)
-			localParties := make([]*LocalParty, N, N)
-			protocol := make([]*Protocol, N, N)
-
-			beaverTriplets := make(map[PartyID]map[WireID]BeaverTriplet)
-			for peerID := range testCase.Peers {
-				beaverTriplets[peerID] = make(map[WireID]BeaverTriplet)
+			for _, p := range runTrustedThirdParty(t, testCase, (*Protocol).Run) {
+				checkOutputs(t, testCase, p)
 			}
+		})
+	}
+}
 
-			for _, op := range testCase.Circuit {
-				if triplets := op.BeaverTriplet(len(testCase.Peers)); triplets != nil {
-					for id, triplet := range triplets {
-						beaverTriplets[PartyID(id)][op.Output()] = triplet
-					}
+// Evaluate the circuits gate by gate, and check that batching the openings by layer saves rounds
+func TestRunSequential(t *testing.T) {
+	for i, testCase := range TestCircuits {
+		t.Run(fmt.Sprintf("circuit%d", i+1), func(t *testing.T) {
+			sequential := runTrustedThirdParty(t, testCase, (*Protocol).RunSequential)
+			batched := runTrustedThirdParty(t, testCase, (*Protocol).Run)
+			for i, p := range sequential {
+				checkOutputs(t, testCase, p)
+				if batched[i].Rounds > p.Rounds {
+					t.Errorf("%s: %d rounds when batched, %d when sequential", p.LocalParty, batched[i].Rounds, p.Rounds)
 				}
 			}
+		})
+	}
 
-			var err error
-			wg := new(sync.WaitGroup)
+	// The three products of Circuit7 are opened together: inputs, products and output take one round each
+	for _, p := range runTrustedThirdParty(t, &Circuit7, (*Protocol).Run) {
+		if p.Rounds != 3 {
+			t.Errorf("%s: Circuit7 evaluated in %d rounds, expected 3", p.LocalParty, p.Rounds)
+		}
+	}
+}
 
-			for i := range testCase.Peers {
-				localParties[i], err = NewLocalParty(i, testCase.Peers)
+// The Beaver triplets generated with BFV are valid, and each multiplication gets its own slot of the batch
+func TestBeaverTripletHE(t *testing.T) {
+	b := NewCircuitBuilder()
+	x, y := b.Input(0), b.Input(1)
+	b.Reveal(x.Mul(y))
+	b.Reveal(x.Mul(x))
+	b.Reveal(y.Mul(y).Mul(x))
+	circuit := b.Circuit()
+
+	peers := testPeers(2)
+	localParties := make([]*LocalParty, len(peers))
+	for i := range peers {
+		lp, err := NewLocalParty(i, peers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		localParties[i] = lp
+	}
+	network := GetTestingTCPNetwork(localParties)
+	beaverTriplets := map[PartyID]map[WireID]BeaverTriplet{0: {}, 1: {}}
+	beaverProtocol := make([]*BeaverProtocol, len(peers))
+	wg := new(sync.WaitGroup)
+	for i, lp := range localParties {
+		lp.BindNetwork(network[i])
+		beaverProtocol[i] = lp.NewBeaverProtocol(Params)
+		wg.Add(1)
+		go func(bp *BeaverProtocol) {
+			defer wg.Done()
+			ComputeBeaverTripletHE(bp, beaverTriplets, circuit)
+		}(beaverProtocol[i])
+	}
+	wg.Wait()
 
-				if err != nil {
-					t.Errorf("creation of new local party failed")
-				}
+	open := func(share0, share1 *big.Int) uint64 {
+		return new(big.Int).Add(share0, share1).Uint64() % Params.T
+	}
+	// The four triplets fit in the slots of a single run of the protocol, and are taken in order
+	seen := make(map[[2]uint64]WireID)
+	for _, op := range circuit {
+		for _, w := range tripletWires(op) {
+			t0, t1 := beaverTriplets[0][w], beaverTriplets[1][w]
+			if slot := beaverProtocol[0].BeaverTriplets.ai[len(seen)]; t0.a.Uint64() != slot {
+				t.Errorf("triplet of wire %d: a = %d, expected slot %d of the batch, %d", w, t0.a, len(seen), slot)
+			}
+			a, b, c := open(t0.a, t1.a), open(t0.b, t1.b), open(t0.c, t1.c)
+			if a*b%Params.T != c {
+				t.Errorf("triplet of wire %d: c = %d, expected a*b = %d", w, c, a*b%Params.T)
+			}
+			if other, reused := seen[[2]uint64{a, b}]; reused {
+				t.Errorf("wires %d and %d have the same triplet", other, w)
+			}
+			seen[[2]uint64{a, b}] = w
+		}
+	}
+	if len(seen) != 4 {
+		t.Errorf("%d triplets, expected 4", len(seen))
+	}
+}
 
-				localParties[i].WaitGroup = wg
+// Check that the party learned the public outputs of the circuit and only its own private outputs
+func checkOutputs(t *testing.T, testCase *TestCircuit, p *Protocol) {
+	if !reflect.DeepEqual(p.Outputs, testCase.ExpOutputs) {
+		t.Errorf("%s: result %v, expected %v", p.LocalParty, p.Outputs, testCase.ExpOutputs)
+	}
+
+	expPrivate := make(map[WireID]uint64)
+	for _, wire := range testCase.Circuit.PrivateOutputWires(p.ID) {
+		expPrivate[wire] = testCase.ExpPrivateOutputs[wire]
+	}
+	if !reflect.DeepEqual(p.PrivateOutputs, expPrivate) {
+		t.Errorf("%s: private result %v, expected %v", p.LocalParty, p.PrivateOutputs, expPrivate)
+	}
+}
+
+// Evaluate the circuit over a local TCP network, the Beaver triplets being generated by a trusted third party
+func runTrustedThirdParty(t *testing.T, testCase *TestCircuit, run func(*Protocol) error) []*Protocol {
+	N := len(testCase.Peers)
+	localParties := make([]*LocalParty, N, N)
+	protocol := make([]*Protocol, N, N)
+
+	beaverTriplets := DealBeaverTriplets(testCase.Circuit, N)
+	randomBits := DealRandomBits(testCase.Circuit, N)
+	matrixTriplets := DealMatrixTriplets(testCase.Circuit, N)
+	binaryTriplets := DealBinaryTriplets(testCase.Circuit, N)
+
+	var err error
+	wg := new(sync.WaitGroup)
+
+	for i := range testCase.Peers {
+		localParties[i], err = NewLocalParty(i, testCase.Peers)
+
+		if err != nil {
+			t.Errorf("creation of new local party failed")
+		}
+
+		localParties[i].WaitGroup = wg
+
+	}
+
+	network := GetTestingTCPNetwork(localParties)
+
+	for i, lp := range localParties {
+		lp.BindNetwork(network[i])
+	}
+
+	for i, lp := range localParties {
+		protocol[i] = lp.NewProtocol(testCase.Inputs[lp.ID], testCase.Circuit, beaverTriplets[lp.ID])
+		protocol[i].RandomBits = randomBits[lp.ID]
+		protocol[i].MatrixTriplets = matrixTriplets[lp.ID]
+		protocol[i].BinaryTriplets = binaryTriplets[lp.ID]
+	}
+
+	for _, p := range protocol {
+		p.Add(1)
+		go func(protocol *Protocol) {
+			defer protocol.Done()
+			if err := run(protocol); err != nil {
+				t.Error(err)
+			}
+		}(p)
+	}
+
+	wg.Wait()
+
+	return protocol
+}
+
+// Returns the addresses of n parties listening on the local ports 6660 onwards
+func testPeers(n int) map[PartyID]string {
+	peers := make(map[PartyID]string, n)
+	for i := 0; i < n; i++ {
+		peers[PartyID(i)] = fmt.Sprintf("localhost:%d", 6660+i)
+	}
+	return peers
+}
+
+// Check the expected outputs of the circuit against its evaluation in clear and with the MPC protocol, which must take
+// the given number of rounds
+func checkCircuit(t *testing.T, testCase *TestCircuit, rounds uint64) {
+	ce, err := EvaluateCircuit(testCase.Circuit, testCase.Inputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ce.Outputs, testCase.ExpOutputs) {
+		t.Errorf("cleartext evaluation: %v, expected %v", ce.Outputs, testCase.ExpOutputs)
+	}
+
+	for _, p := range runTrustedThirdParty(t, testCase, (*Protocol).Run) {
+		checkOutputs(t, testCase, p)
+		if p.Rounds != rounds {
+			t.Errorf("%s: %d rounds, expected %d", p.LocalParty, p.Rounds, rounds)
+		}
+	}
+}
+
+// Evaluate random circuits with the MPC protocol and check their outputs against the evaluation in clear. A failing
+// circuit is shrunk to a minimal failing circuit before being reported
+func TestRandomCircuits(t *testing.T) {
+	iterations := 40
+	if testing.Short() {
+		iterations = 5
+	}
 
+	fails := func(testCase *TestCircuit) bool {
+		for _, p := range runTrustedThirdParty(t, testCase, (*Protocol).Run) {
+			if !reflect.DeepEqual(p.Outputs, testCase.ExpOutputs) {
+				return true
 			}
+		}
+		return false
+	}
 
-			network := GetTestingTCPNetwork(localParties)
+	for seed := 1; seed <= iterations; seed++ {
+		rng := rand.New(rand.NewSource(int64(seed)))
+		testCase := RandomCircuit(rng, 2+rng.Intn(5), 1+rng.Intn(30))
+		if fails(testCase) {
+			minimal := shrinkCircuit(testCase, fails)
+			data, _ := json.Marshal(minimal)
+			t.Errorf("seed %d: MPC and cleartext results differ, minimal failing circuit: %s", seed, data)
+		}
+	}
+}
 
-			for i, lp := range localParties {
-				lp.BindNetwork(network[i])
+// Remove operations from the circuit as long as it keeps failing. A removed gate is replaced by its first input
+func shrinkCircuit(testCase *TestCircuit, fails func(*TestCircuit) bool) *TestCircuit {
+	for shrunk := true; shrunk; {
+		shrunk = false
+		for i := len(testCase.Circuit) - 1; i >= 0 && !shrunk; i-- {
+			if candidate := withoutOperation(testCase, i); candidate != nil && fails(candidate) {
+				testCase = candidate
+				shrunk = true
 			}
+		}
+	}
+	return testCase
+}
 
-			for i, lp := range localParties {
-				protocol[i] = lp.NewProtocol(testCase.Inputs[lp.ID][GateID(i)], testCase.Circuit, beaverTriplets[lp.ID])
+// Returns the test circuit without its i-th operation, or nil if it cannot be removed
+func withoutOperation(testCase *TestCircuit, i int) *TestCircuit {
+	removed := testCase.Circuit[i]
+	if len(testCase.Circuit.OutputWires()) == 1 && len(Circuit{removed}.OutputWires()) == 1 {
+		return nil
+	}
+
+	candidate := *testCase
+	replacement := removed.Output()
+	if in, isInput := removed.(inputOperation); isInput {
+		for _, op := range testCase.Circuit {
+			for _, w := range op.Inputs() {
+				if w == removed.Output() {
+					return nil
+				}
 			}
+		}
+		candidate.Inputs = make(map[PartyID]map[GateID]uint64, len(testCase.Inputs))
+		for id, inputs := range testCase.Inputs {
+			candidate.Inputs[id] = make(map[GateID]uint64, len(inputs))
+			for gate, value := range inputs {
+				if id != in.Owner() || gate != GateID(removed.Output()) {
+					candidate.Inputs[id][gate] = value
+				}
+			}
+		}
+	} else {
+		replacement = removed.Inputs()[0]
+	}
 
-			for _, p := range protocol {
-				p.Add(1)
-				go func(protocol *Protocol) {
-					defer protocol.Done()
-					protocol.Run()
-				}(p)
+	remap := func(w WireID) WireID {
+		if w == removed.Output() {
+			return replacement
+		}
+		return w
+	}
+
+	candidate.Circuit = nil
+	for j, op := range testCase.Circuit {
+		if j != i {
+			candidate.Circuit = append(candidate.Circuit, op.Remap(remap))
+		}
+	}
+
+	ce, err := EvaluateCircuit(candidate.Circuit, candidate.Inputs)
+	if err != nil {
+		return nil
+	}
+	candidate.ExpOutputs = ce.Outputs
+	return &candidate
+}
+
+// Serialize every circuit defined in test_circuit.go and check that it is decoded back to the same circuit
+func TestCircuitJSON(t *testing.T) {
+	for i, testCase := range TestCircuits {
+		t.Run(fmt.Sprintf("circuit%d", i+1), func(t *testing.T) {
+			data, err := json.Marshal(testCase)
+			if err != nil {
+				t.Fatal(err)
 			}
 
-			wg.Wait()
+			decoded := new(TestCircuit)
+			if err := json.Unmarshal(data, decoded); err != nil {
+				t.Fatal(err)
+			}
 
-			for _, p := range protocol {
-				if p.Output != testCase.ExpOutput {
-					t.Errorf(p.LocalParty.String(), "result", p.Output, "expected", testCase.ExpOutput)
+			if !reflect.DeepEqual(testCase, decoded) {
+				t.Errorf("decoded circuit differs from the original: %s", data)
+			}
+		})
+	}
+
+	path := filepath.Join(os.TempDir(), "mpc_circuit_test.json")
+	defer os.Remove(path)
+	if err := WriteCircuitFile(path, &Circuit10); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := ReadCircuitFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&Circuit10, decoded) {
+		t.Errorf("circuit read from %s differs from the original", path)
+	}
+
+	if err := json.Unmarshal([]byte(`[{"type":"Mod","In1":0,"In2":1,"Out":2}]`), new(Circuit)); err == nil {
+		t.Errorf("unknown operation type should be rejected")
+	}
+}
+
+// Compile arithmetic expressions and check the result of their evaluation
+func TestCompileExpression(t *testing.T) {
+	parties := map[string]PartyID{"x": 0, "y": 1, "z": 2}
+	peers := map[PartyID]string{
+		0: "localhost:6660",
+		1: "localhost:6661",
+		2: "localhost:6662",
+	}
+	inputs := map[PartyID]map[GateID]uint64{
+		0: {0: 9},
+		1: {1: 5},
+		2: {2: 7},
+	}
+
+	testCases := []struct {
+		expr      string
+		expOutput uint64
+		mults     int
+	}{
+		{"x + y + z", 21, 0},
+		{"6 + 6*(x+y-z) + 3*(x+y-z)^2 + (x+y-z)^3", 538, 3},
+		{"2*x*3 - y + 1", 50, 0},
+		{"10 - x", 1, 0},
+		{"-x*y + z^0*4", 65537 - 41, 1},
+		{"x*y*z", 315, 2},
+		{"(x - y)^5", 1024, 3},
+	}
+
+	for i, testCase := range testCases {
+		t.Run(fmt.Sprintf("expr%d", i+1), func(t *testing.T) {
+			circuit, err := CompileExpression(testCase.expr, parties)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ce, err := EvaluateCircuit(circuit, inputs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ce.Outputs[circuit.OutputWires()[0]] != testCase.expOutput {
+				t.Errorf("%q evaluated in clear to %v, expected %d", testCase.expr, ce.Outputs, testCase.expOutput)
+			}
+
+			mults := 0
+			for _, op := range circuit {
+				if op.IsMult() {
+					mults++
 				}
 			}
+			if mults != testCase.mults {
+				t.Errorf("%q compiled with %d Mult gates, expected %d", testCase.expr, mults, testCase.mults)
+			}
 
+			for _, p := range runTrustedThirdParty(t, &TestCircuit{Peers: peers, Inputs: inputs, Circuit: circuit}, (*Protocol).Run) {
+				if len(p.Outputs) != 1 || p.Outputs[circuit.OutputWires()[0]] != testCase.expOutput {
+					t.Errorf("%s: %q evaluated to %v, expected %d", p.LocalParty, testCase.expr, p.Outputs, testCase.expOutput)
+				}
+			}
 		})
 	}
+
+	syntaxErrors := map[string]int{
+		"x + ":      5,
+		"x + (y":    7,
+		"x $ y":     3,
+		"x + w":     5,
+		"x ^ y":     5,
+		"(x + y) z": 9,
+	}
+	for expr, pos := range syntaxErrors {
+		_, err := CompileExpression(expr, parties)
+		if synErr, ok := err.(*SyntaxError); !ok || synErr.Pos != pos {
+			t.Errorf("%q: expected a syntax error at position %d, got %v", expr, pos, err)
+		}
+	}
+
+	if _, err := CompileExpression("3 * 4", parties); err == nil {
+		t.Errorf("constant expression should be rejected")
+	}
+
+	// Each variable is the input of its own party
+	for _, inputs := range []string{"", " ", "x=1,x=2", "x=1, y=2 ,y =3", "=1"} {
+		if _, err := expressionCircuit("x + y", inputs, 0); err == nil {
+			t.Errorf("inputs %q should be rejected", inputs)
+		}
+	}
+	testCircuit, err := expressionCircuit("x + y", "x=1, y=2", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(testCircuit.Peers) != 2 || ValidateInputs(testCircuit.Circuit, 1, testCircuit.Inputs[1]) != nil {
+		t.Errorf("unexpected circuit %+v", testCircuit)
+	}
+
+	// The outputs of other circuits are not decoded as fixed-point numbers
+	if _, err := (&circuitFlags{id: 1, frac: 2}).load(); err == nil {
+		t.Errorf("-frac should be rejected without -expr")
+	}
+	if _, err := (&circuitFlags{expr: "x * y", inputs: "x=1.5, y=-2", frac: 2}).load(); err != nil {
+		t.Error(err)
+	}
+}
+
+// Check that each kind of invalid circuit is reported
+func TestValidateCircuit(t *testing.T) {
+	peers := map[PartyID]string{0: "localhost:6660", 1: "localhost:6661"}
+
+	for i, testCase := range TestCircuits {
+		if err := ValidateCircuit(testCase.Circuit, testCase.Peers, nil); err != nil {
+			t.Errorf("circuit%d: %s", i+1, err)
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		circuit  Circuit
+		triplets map[WireID]BeaverTriplet
+		expKind  ValidationErrorKind
+		expIndex int
+	}{
+		{"undefined", Circuit{&Input{0, 0}, &Add{0, 5, 1}, &Reveal{1, 2}}, nil, UndefinedWire, 1},
+		{"unordered", Circuit{&Input{0, 0}, &Add{0, 2, 1}, &Input{1, 2}, &Reveal{1, 3}}, nil, UnorderedWire, 1},
+		{"duplicate", Circuit{&Input{0, 0}, &Input{1, 0}, &Reveal{0, 1}}, nil, DuplicateWire, 1},
+		{"unknown party", Circuit{&Input{0, 0}, &Input{2, 1}, &Reveal{1, 2}}, nil, UnknownParty, 1},
+		{"no reveal", Circuit{&Input{0, 0}, &Input{1, 1}, &Add{0, 1, 2}}, nil, MissingReveal, -1},
+		{"no triplet", Circuit{&Input{0, 0}, &Input{1, 1}, &Mult{0, 1, 2}, &Reveal{2, 3}}, map[WireID]BeaverTriplet{}, MissingTriplet, 2},
+		{"unknown recipient", Circuit{&Input{0, 0}, &RevealTo{0, 1, 2}}, nil, UnknownParty, 1},
+		{"private wire", Circuit{&Input{0, 0}, &RevealTo{0, 1, 1}, &AddCst{1, 2, 2}, &Reveal{2, 3}}, nil, PrivateWire, 2},
+	}
+
+	for _, testCase := range testCases {
+		err := ValidateCircuit(testCase.circuit, peers, testCase.triplets)
+		errs, ok := err.(ValidationErrors)
+		if !ok || len(errs) != 1 {
+			t.Errorf("%s: expected one validation error, got %v", testCase.name, err)
+			continue
+		}
+		if errs[0].Kind != testCase.expKind || errs[0].Index != testCase.expIndex {
+			t.Errorf("%s: unexpected error %q", testCase.name, errs[0])
+		}
+	}
+
+	for id := range Circuit12.Peers {
+		if err := ValidateInputs(Circuit12.Circuit, id, Circuit12.Inputs[id]); err != nil {
+			t.Errorf("party %d: %s", id, err)
+		}
+	}
+	err := ValidateInputs(Circuit12.Circuit, 0, map[GateID]uint64{0: 3200, 2: 3900})
+	if errs, ok := err.(ValidationErrors); !ok || len(errs) != 1 || errs[0].Kind != MissingInput || errs[0].Wire != 1 {
+		t.Errorf("expected missing input for wire 1, got %v", err)
+	}
+}
+
+// Evaluate the circuits of test_circuits.go in clear and check their expected outputs
+func TestEvaluateCircuit(t *testing.T) {
+	for i, testCase := range TestCircuits {
+		ce, err := EvaluateCircuit(testCase.Circuit, testCase.Inputs)
+		if err != nil {
+			t.Errorf("circuit%d: %s", i+1, err)
+			continue
+		}
+		if !reflect.DeepEqual(ce.Outputs, testCase.ExpOutputs) {
+			t.Errorf("circuit%d: result %v, expected %v", i+1, ce.Outputs, testCase.ExpOutputs)
+		}
+		if len(ce.PrivateOutputs) != len(testCase.ExpPrivateOutputs) || len(ce.PrivateOutputs) > 0 && !reflect.DeepEqual(ce.PrivateOutputs, testCase.ExpPrivateOutputs) {
+			t.Errorf("circuit%d: private result %v, expected %v", i+1, ce.PrivateOutputs, testCase.ExpPrivateOutputs)
+		}
+	}
+
+	if _, err := EvaluateCircuit(Circuit12.Circuit, map[PartyID]map[GateID]uint64{0: {0: 1, 1: 2, 2: 3}, 1: {3: 4}}); err == nil {
+		t.Errorf("missing input should be reported")
+	}
+
+	// The recipient of a private output needs no input
+	revealTo := Circuit{&Input{Party: 0, Out: 0}, &RevealTo{In: 0, Party: 1, Out: 1}}
+	ce, err := EvaluateCircuit(revealTo, map[PartyID]map[GateID]uint64{0: {0: 42}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ce.PrivateOutputs, map[WireID]uint64{1: 42}) {
+		t.Errorf("private result %v, expected 42", ce.PrivateOutputs)
+	}
+}
+
+// Export a circuit to DOT and check its nodes, edges and annotations
+func TestWriteDOT(t *testing.T) {
+	var sb strings.Builder
+	if err := WriteDOT(&sb, Circuit13.Circuit); err != nil {
+		t.Fatal(err)
+	}
+	dot := sb.String()
+
+	if nodes := strings.Count(dot, "fillcolor="); nodes != len(Circuit13.Circuit) {
+		t.Errorf("%d nodes, expected %d", nodes, len(Circuit13.Circuit))
+	}
+	edges := 0
+	for _, op := range Circuit13.Circuit {
+		edges += len(op.Inputs())
+	}
+	if n := strings.Count(dot, "->"); n != edges {
+		t.Errorf("%d edges, expected %d", n, edges)
+	}
+
+	expected := []string{
+		`op1 [label="Input (Party: 1)\nout: wire 1\ndepth: 0", fillcolor=lightblue]`,
+		`op6 [label="Mult\nout: wire 6\ndepth: 1", fillcolor=salmon]`,
+		`op7 [label="RevealTo (Party: 0)\nout: wire 7\ndepth: 1", fillcolor=palegreen]`,
+		`op8 [label="MultCst (CstValue: 2)\nout: wire 8\ndepth: 0", fillcolor=white]`,
+		`op0 -> op6 [label="w0"]`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(dot, line) {
+			t.Errorf("missing %s in:\n%s", line, dot)
+		}
+	}
+}
+
+// Check the estimated cost of circuits against hand-computed values and against the rounds of actual evaluations
+func TestComputeStats(t *testing.T) {
+	stats, err := ComputeStats(Circuit7.Circuit, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &CircuitStats{
+		Parties:          3,
+		Gates:            map[string]int{"Input": 3, "Mult": 3, "Add": 2, "Reveal": 1},
+		MultDepth:        1,
+		BeaverTriplets:   3,
+		HEBatches:        1,
+		Rounds:           3,
+		SequentialRounds: 7,
+		// inputs: 3 parties * 2 peers * (16 + 8), products: 6 * (16 + 6*8), output: 6 * (16 + 8)
+		OnlineBytes:          144 + 384 + 144,
+		HEPreprocessingBytes: stats.HEPreprocessingBytes,
+	}
+	if !reflect.DeepEqual(stats, expected) {
+		t.Errorf("Circuit7: stats %+v, expected %+v", stats, expected)
+	}
+
+	stats, err = ComputeStats(Circuit13.Circuit, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Layer 0 opens the product operands and the public output to all and the sum to party 2 only, layer 1 opens the
+	// product to party 0 only
+	if layer0 := uint64(4*(16+3*8) + 2*(16+4*8)); stats.OnlineBytes != 144+layer0+2*(16+8) {
+		t.Errorf("Circuit13: %d bytes sent", stats.OnlineBytes)
+	}
+
+	for i, testCase := range TestCircuits {
+		stats, err := ComputeStats(testCase.Circuit, len(testCase.Peers))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range runTrustedThirdParty(t, testCase, (*Protocol).Run) {
+			if p.Rounds != uint64(stats.Rounds) {
+				t.Errorf("circuit%d: %s evaluated in %d rounds, %d estimated", i+1, p.LocalParty, p.Rounds, stats.Rounds)
+			}
+		}
+	}
+}
+
+// Optimize circuits and check that they compute the same outputs with fewer gates
+func TestOptimize(t *testing.T) {
+	countMults := func(circuit Circuit) (count int) {
+		for _, op := range circuit {
+			if op.IsMult() {
+				count++
+			}
+		}
+		return count
+	}
+
+	b := NewCircuitBuilder()
+	x, y := b.Input(0), b.Input(1)
+	three := x.Sub(x).AddConst(3)
+	b.Reveal(x.Mul(y))
+	b.Reveal(y.Mul(three))                         // Mult by a constant wire, rewritten into a MultCst
+	b.Reveal(x.AddConst(1).AddConst(2).Add(three)) // chained AddCst, merged into a single one
+	b.Reveal(x.Add(y).Mul(y.Add(x)))               // common subexpression
+	b.Reveal(x.MulConst(1))
+	x.Mul(x) // dead gate
+	inputs := map[PartyID]map[GateID]uint64{0: {GateID(x.ID): 11}, 1: {GateID(y.ID): 8}}
+	circuit := b.Circuit()
+
+	optimized := Optimize(circuit)
+	if err := ValidateCircuit(optimized, map[PartyID]string{0: "", 1: ""}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if mults := countMults(optimized); mults != 2 {
+		t.Errorf("%d multiplications left, expected 2", mults)
+	}
+	for _, op := range optimized {
+		if addCst, isAddCst := op.(*AddCst); isAddCst && addCst.In == x.ID && addCst.CstValue != 6 {
+			t.Errorf("chained constants not merged: %v", addCst)
+		}
+	}
+
+	expected, err := EvaluateCircuit(circuit, inputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := EvaluateCircuit(optimized, inputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.Outputs, expected.Outputs) {
+		t.Errorf("result %v, expected %v", result.Outputs, expected.Outputs)
+	}
+
+	// The repeated x+y-z of the compiled expression of Circuit10 is computed once, saving one multiplication
+	expr, err := CompileExpression("6 + 6*(x+y-z) + 3*(x+y-z)^2 + (x+y-z)^3", map[string]PartyID{"x": 0, "y": 1, "z": 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCase := Circuit10
+	testCase.Circuit = Optimize(expr)
+	testCase.ExpOutputs = map[WireID]uint64{testCase.Circuit.OutputWires()[0]: 538}
+	if mults := countMults(testCase.Circuit); mults != 2 {
+		t.Errorf("circuit10: %d multiplications left, expected 2", mults)
+	}
+	for _, p := range runTrustedThirdParty(t, &testCase, (*Protocol).Run) {
+		checkOutputs(t, &testCase, p)
+	}
+
+	// The elements of a vector computed by scalar gates, one adding 0 and one duplicated, are not replaced by other wires
+	vectorCircuit := Circuit{
+		&Input{Party: 0, Out: 0},
+		&Input{Party: 1, Out: 1},
+		&AddCst{In: 0, CstValue: 0, Out: 2},
+		&AddCst{In: 1, CstValue: 5, Out: 3},
+		&AddCst{In: 1, CstValue: 5, Out: 4},
+		&VecAdd{In1: 2, In2: 2, Out: 5, Len: 2},
+		&VecAdd{In1: 3, In2: 3, Out: 7, Len: 2},
+		&VecReveal{In: 5, Out: 9, Len: 2},
+		&VecReveal{In: 7, Out: 11, Len: 2},
+	}
+	inputs = map[PartyID]map[GateID]uint64{0: {0: 10}, 1: {1: 20}}
+	expected, err = EvaluateCircuit(vectorCircuit, inputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err = EvaluateCircuit(Optimize(vectorCircuit), inputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.Outputs, expected.Outputs) || result.Outputs[10] != 50 {
+		t.Errorf("vector of scalar gates: result %v, expected %v", result.Outputs, expected.Outputs)
+	}
+
+	// A gate failing on constant inputs is not folded: the optimized circuit fails the same way
+	for i, failing := range []func(x, y Wire) Wire{
+		func(x, y Wire) Wire { return y.Mul(x.Sub(x).Inverse()) },
+		func(x, y Wire) Wire { return y.Mul(x.Sub(x).AddConst(3).Div(x.Sub(x))) },
+		func(x, y Wire) Wire { return y.Mul(x.Sub(x).AddConst(2).SelectChecked(x, x)) },
+		func(x, y Wire) Wire {
+			return y.Mul(x.Sub(x).AddConst(400).MulFixed(x.Sub(x).AddConst(400), FixedPoint{FracBits: 2}))
+		},
+	} {
+		b := NewCircuitBuilder()
+		x, y := b.Input(0), b.Input(1)
+		b.Reveal(failing(x, y))
+		inputs := map[PartyID]map[GateID]uint64{0: {GateID(x.ID): 11}, 1: {GateID(y.ID): 8}}
+		_, expected := EvaluateCircuit(b.Circuit(), inputs)
+		_, err := EvaluateCircuit(Optimize(b.Circuit()), inputs)
+		if expected == nil || reflect.TypeOf(err) != reflect.TypeOf(expected) {
+			t.Errorf("constant gate %d: got error %v, expected %v", i, err, expected)
+		}
+	}
+
+	for seed := 1; seed <= 200; seed++ {
+		rng := rand.New(rand.NewSource(int64(seed)))
+		testCase := RandomCircuit(rng, 2+rng.Intn(3), 1+rng.Intn(50))
+		optimized := Optimize(testCase.Circuit)
+		if countMults(optimized) > countMults(testCase.Circuit) {
+			t.Errorf("seed %d: optimized circuit has more multiplications", seed)
+		}
+		result, err := EvaluateCircuit(optimized, testCase.Inputs)
+		if err != nil {
+			t.Errorf("seed %d: %s", seed, err)
+		} else if !reflect.DeepEqual(result.Outputs, testCase.ExpOutputs) {
+			t.Errorf("seed %d: result %v, expected %v", seed, result.Outputs, testCase.ExpOutputs)
+		}
+	}
+}
+
+// Multiply two large vectors element-wise: all the masked elements are opened in a single round
+func TestVectorOperations(t *testing.T) {
+	const n = 10000
+	b := NewCircuitBuilder()
+	x, y := b.VectorInput(0, n), b.VectorInput(1, n)
+	z := b.RevealVector(x.Mul(y).Sub(y).AddConst(1))
+
+	testCase := &TestCircuit{
+		Peers:      testPeers(3),
+		Inputs:     map[PartyID]map[GateID]uint64{0: {}, 1: {}},
+		Circuit:    b.Circuit(),
+		ExpOutputs: make(map[WireID]uint64, n),
+	}
+	for i := 0; i < n; i++ {
+		testCase.Inputs[0][GateID(x.Elem(i).ID)] = uint64(i)
+		testCase.Inputs[1][GateID(y.Elem(i).ID)] = 3
+		testCase.ExpOutputs[z.Elem(i).ID] = uint64(3*i - 2)
+	}
+	testCase.ExpOutputs[z.Elem(0).ID] = Params.T - 2
+
+	stats, err := ComputeStats(testCase.Circuit, len(testCase.Peers))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.BeaverTriplets != n || stats.Rounds != 3 {
+		t.Errorf("%d triplets and %d rounds, expected %d and 3", stats.BeaverTriplets, stats.Rounds, n)
+	}
+
+	err = ValidateCircuit(Circuit{&VecInput{Party: 0, Out: 0, Len: 0}, &Reveal{In: 0, Out: 1}}, testCase.Peers, nil)
+	if errs, ok := err.(ValidationErrors); !ok || errs[0].Kind != EmptyVector {
+		t.Errorf("empty vector not reported: %v", err)
+	}
+
+	// A negative length read from a circuit file is reported instead of panicking
+	var negative Circuit
+	if err := json.Unmarshal([]byte(`[{"type":"VecInput","Party":0,"Out":0,"Len":-1},{"type":"VecReveal","In":0,"Out":1,"Len":-1}]`), &negative); err != nil {
+		t.Fatal(err)
+	}
+	err = ValidateCircuit(negative, testCase.Peers, nil)
+	if errs, ok := err.(ValidationErrors); !ok || len(errs) < 2 || errs[0].Kind != InvalidLength || errs[1].Kind != InvalidLength {
+		t.Errorf("negative lengths not reported: %v", err)
+	}
+
+	checkCircuit(t, testCase, 3)
+
+	// Gate by gate, each vector input takes its own round
+	for _, p := range runTrustedThirdParty(t, testCase, (*Protocol).RunSequential) {
+		checkOutputs(t, testCase, p)
+		if p.Rounds != 4 {
+			t.Errorf("%s: %d rounds, expected 4", p.LocalParty, p.Rounds)
+		}
+	}
+}
+
+// Compare pairs of values at the edges of the comparable range, and check the results against the cleartext evaluation
+func TestLessThan(t *testing.T) {
+	pairs := [][2]uint64{{0, 0}, {0, 1}, {1, 0}, {0, MaxComparable}, {MaxComparable, 0}, {MaxComparable, MaxComparable},
+		{MaxComparable - 1, MaxComparable}, {12345, 12344}, {777, 777}}
+
+	b := NewCircuitBuilder()
+	testCase := &TestCircuit{
+		Peers:      testPeers(3),
+		Inputs:     map[PartyID]map[GateID]uint64{0: {}, 1: {}},
+		ExpOutputs: make(map[WireID]uint64),
+	}
+	for _, pair := range pairs {
+		x, y := b.Input(0), b.Input(1)
+		testCase.Inputs[0][GateID(x.ID)] = pair[0]
+		testCase.Inputs[1][GateID(y.ID)] = pair[1]
+
+		lt, gt := b.Reveal(x.LessThan(y)), b.Reveal(x.GreaterThan(y))
+		min, max := b.Reveal(x.Min(y)), b.Reveal(x.Max(y))
+		testCase.ExpOutputs[lt.ID], testCase.ExpOutputs[gt.ID] = 0, 0
+		testCase.ExpOutputs[min.ID], testCase.ExpOutputs[max.ID] = pair[0], pair[1]
+		if pair[0] < pair[1] {
+			testCase.ExpOutputs[lt.ID] = 1
+		} else {
+			testCase.ExpOutputs[min.ID], testCase.ExpOutputs[max.ID] = pair[1], pair[0]
+			if pair[0] > pair[1] {
+				testCase.ExpOutputs[gt.ID] = 1
+			}
+		}
+	}
+	testCase.Circuit = b.Circuit()
+
+	// The products of Min and Max take one more round after the comparisons
+	checkCircuit(t, testCase, uint64(2+len(lessThanLevels)+2+1))
+
+	lessThan := Circuit{&Input{Party: 0, Out: 0}, &Input{Party: 1, Out: 1}, &LessThan{In1: 0, In2: 1, Out: 2}}
+	err := ValidateRandomBits(lessThan, map[WireID]*big.Int{})
+	if errs, ok := err.(ValidationErrors); !ok || errs[0].Kind != MissingRandomBit {
+		t.Errorf("missing random bits not reported: %v", err)
+	}
+}
+
+// Test the equality of values over the whole field, including values differing by a multiple of a power of 2
+func TestEqual(t *testing.T) {
+	pairs := [][2]uint64{{0, 0}, {0, 1}, {Params.T - 1, Params.T - 1}, {0, Params.T - 1}, {Params.T - 1, 0},
+		{1 << 16, 0}, {12345, 12345}, {12345, 12345 + 1<<10}}
+
+	b := NewCircuitBuilder()
+	testCase := &TestCircuit{
+		Peers:      testPeers(3),
+		Inputs:     map[PartyID]map[GateID]uint64{0: {}, 1: {}},
+		ExpOutputs: make(map[WireID]uint64),
+	}
+	for _, pair := range pairs {
+		x, y := b.Input(0), b.Input(1)
+		testCase.Inputs[0][GateID(x.ID)] = pair[0]
+		testCase.Inputs[1][GateID(y.ID)] = pair[1]
+		eq := b.Reveal(x.Equal(y))
+		testCase.ExpOutputs[eq.ID] = 0
+		if pair[0] == pair[1] {
+			testCase.ExpOutputs[eq.ID] = 1
+		}
+	}
+	testCase.Circuit = b.Circuit()
+
+	checkCircuit(t, testCase, uint64(2+len(lessThanLevels)+1))
+}
+
+// Decompose values at the edges of the field, whose masked value does or does not wrap around q
+func TestBitDecompose(t *testing.T) {
+	values := []uint64{0, 1, 2, MaxComparable, 1 << 15, 1<<16 - 1, 1 << 16, 12345, 54321}
+
+	b := NewCircuitBuilder()
+	testCase := &TestCircuit{
+		Peers:      testPeers(3),
+		Inputs:     map[PartyID]map[GateID]uint64{0: {}},
+		ExpOutputs: make(map[WireID]uint64),
+	}
+	for _, value := range values {
+		x := b.Input(0)
+		testCase.Inputs[0][GateID(x.ID)] = value
+		bits := b.RevealVector(x.Bits())
+		for i := 0; i < bits.Len; i++ {
+			testCase.ExpOutputs[bits.Elem(i).ID] = value >> uint(i) & 1
+		}
+	}
+	testCase.Circuit = b.Circuit()
+
+	checkCircuit(t, testCase, uint64(2+len(bitDecomposeLevels)+3))
+}
+
+// Deal Beaver triplets to the parties on demand, the i-th triplet consumed by each party being the same
+type tripletDealer struct {
+	sync.Mutex
+	parties  int
+	triplets [][]BeaverTriplet
+}
+
+func (d *tripletDealer) nextTriplet(id PartyID) func() BeaverTriplet {
+	index := 0
+	return func() BeaverTriplet {
+		d.Lock()
+		defer d.Unlock()
+		if index == len(d.triplets) {
+			d.triplets = append(d.triplets, Mult{}.BeaverTriplet(d.parties))
+		}
+		index++
+		return d.triplets[index-1][id]
+	}
+}
+
+// Generate the random bits of a circuit with the parties, and check that they are bits and that the values whose
+// bits they are lie below q
+func TestRandomBits(t *testing.T) {
+	testCase := Circuit15
+	testCase.Circuit = append(append(Circuit{}, Circuit15.Circuit...), &BitDecompose{In: 0, Out: 200})
+	N := len(testCase.Peers)
+
+	dealer := &tripletDealer{parties: N}
+	localParties := make([]*LocalParty, N)
+	randomBits := make([]map[WireID]*big.Int, N)
+	wg := new(sync.WaitGroup)
+	for i := range localParties {
+		lp, err := NewLocalParty(PartyID(i), testCase.Peers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		localParties[i] = lp
+	}
+	network := GetTestingTCPNetwork(localParties)
+	for i, lp := range localParties {
+		lp.BindNetwork(network[i])
+	}
+	for i, lp := range localParties {
+		wg.Add(1)
+		go func(i int, lp *LocalParty) {
+			defer wg.Done()
+			randomBits[i] = lp.NewRandomBitProtocol(dealer.nextTriplet(lp.ID)).Generate(testCase.Circuit)
+		}(i, lp)
+	}
+	wg.Wait()
+
+	if expected := randomBitTriplets(testCase.Circuit); len(dealer.triplets) < expected {
+		t.Errorf("%d triplets consumed, expected at least %d", len(dealer.triplets), expected)
+	}
+
+	if err := ValidateRandomBits(testCase.Circuit, randomBits[0]); err != nil {
+		t.Fatal(err)
+	}
+	for _, op := range testCase.Circuit {
+		ro, isRandom := op.(randomBitsOperation)
+		if !isRandom {
+			continue
+		}
+		value := big.NewInt(0)
+		for i, w := range ro.RandomBitWires() {
+			bit := big.NewInt(0)
+			for _, bits := range randomBits {
+				bit.Add(bit, bits[w])
+			}
+			if bit.Mod(bit, q); bit.Uint64() > 1 {
+				t.Fatalf("wire %d: %d is not a bit", w, bit)
+			}
+			value.SetBit(value, i, uint(bit.Uint64()))
+		}
+		if value.Cmp(q) >= 0 {
+			t.Errorf("%T: random value %d is not below q", op, value)
+		}
+	}
+}
+
+// Multiply fixed-point numbers of both signs, and check that the error of the probabilistic truncation is below
+// 2^-FracBits
+func TestFixedPoint(t *testing.T) {
+	fp := FixedPoint{FracBits: 3}
+	for _, x := range []float64{0, 1.125, -1.125, 3.5, -2.75, float64(MaxComparable) / 8, -float64(MaxComparable) / 8} {
+		encoded, err := fp.Encode(x)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded := fp.Decode(encoded); decoded != x {
+			t.Errorf("%g decoded as %g", x, decoded)
+		}
+	}
+	if _, err := fp.Encode(4097); err == nil {
+		t.Errorf("out of range number should not be encoded")
+	}
+
+	// Products of encoded numbers in [-2^(FixedPointBits-1), 2^(FixedPointBits-1)), i.e. below 8 once decoded
+	pairs := [][2]float64{{1.125, 2.5}, {-1.125, 2.5}, {0.375, -0.625}, {-1.875, -3.875}, {2.875, 2.625}, {0, -1.5}, {0.125, 0.125}}
+	b := NewCircuitBuilder()
+	testCase := &TestCircuit{
+		Peers:  testPeers(3),
+		Inputs: map[PartyID]map[GateID]uint64{0: {}, 1: {}},
+	}
+	expected := make(map[WireID]float64)
+	for _, pair := range pairs {
+		x, y := b.Input(0), b.Input(1)
+		testCase.Inputs[0][GateID(x.ID)], _ = fp.Encode(pair[0])
+		testCase.Inputs[1][GateID(y.ID)], _ = fp.Encode(pair[1])
+		expected[b.Reveal(x.MulFixed(y, fp)).ID] = pair[0] * pair[1]
+	}
+	testCase.Circuit = b.Circuit()
+
+	// Evaluated many times, since the error is random
+	for i := 0; i < 5; i++ {
+		for _, p := range runTrustedThirdParty(t, testCase, (*Protocol).Run) {
+			for w, product := range expected {
+				if result := fp.Decode(p.Outputs[w]); math.Abs(result-product) >= 0.125 {
+					t.Errorf("%s: wire %d = %g, expected %g", p.LocalParty, w, result, product)
+				}
+			}
+		}
+	}
+
+	circuit, err := CompileFixedExpression("x*y + 2*x - 1", map[string]PartyID{"x": 0, "y": 1}, fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, _ := fp.Encode(1.5)
+	y, _ := fp.Encode(-0.5)
+	ce, err := EvaluateCircuit(circuit, map[PartyID]map[GateID]uint64{0: {0: x}, 1: {1: y}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := fp.Decode(ce.Outputs[circuit[len(circuit)-1].Output()]); result != 1.25 {
+		t.Errorf("x*y + 2*x - 1 = %g, expected 1.25", result)
+	}
+
+	// A product out of range is reported in clear, while the protocol gives a wrong result, here with a mask r = 0
+	tooLarge := Circuit{&Input{Party: 0, Out: 0}, &Input{Party: 1, Out: 1}, &MultFixed{In1: 0, In2: 1, Out: 2, FracBits: fp.FracBits}, &Reveal{In: 2, Out: 20}}
+	x, _ = fp.Encode(-4)
+	y, _ = fp.Encode(4)
+	testCase = &TestCircuit{Peers: testCase.Peers, Inputs: map[PartyID]map[GateID]uint64{0: {0: x}, 1: {1: y}}, Circuit: tooLarge}
+	if _, err := EvaluateCircuit(tooLarge, testCase.Inputs); err == nil {
+		t.Errorf("product out of range not reported")
+	} else if rangeErr, ok := err.(*FixedPointRangeError); !ok || rangeErr.Product != -1024 {
+		t.Errorf("unexpected error: %v", err)
+	}
+	for _, p := range runTrustedThirdParty(t, testCase, func(p *Protocol) error {
+		for w := range p.RandomBits {
+			p.RandomBits[w] = big.NewInt(0)
+		}
+		return p.Run()
+	}) {
+		if result := fp.Decode(p.Outputs[20]); math.Abs(result+16) < 1 {
+			t.Errorf("%s: product out of range computed as %g", p.LocalParty, result)
+		}
+	}
+
+	// Gates given by value are checked too
+	for _, op := range []Operation{&MultFixed{In1: 0, In2: 0, Out: 1, FracBits: FixedPointBits}, MultFixed{In1: 0, In2: 0, Out: 1, FracBits: FixedPointBits}} {
+		invalid := Circuit{&Input{Party: 0, Out: 0}, op, &Reveal{In: 1, Out: 20}}
+		err = ValidateCircuit(invalid, testCase.Peers, nil)
+		if errs, ok := err.(ValidationErrors); !ok || len(errs) != 1 || errs[0].Kind != InvalidFracBits || errs[0].Value != FixedPointBits {
+			t.Errorf("invalid number of fractional bits not reported: %v", err)
+		}
+	}
+}
+
+// Invert and divide values, including values whose inverse wraps around q, and check that a division by zero stops
+// the evaluation of all the parties with a ZeroInverseError
+func TestInverse(t *testing.T) {
+	pairs := [][2]uint64{{1, 1}, {6000, 3}, {1, 2}, {Params.T - 1, Params.T - 1}, {0, 12345}, {54321, 2}}
+
+	b := NewCircuitBuilder()
+	testCase := &TestCircuit{
+		Peers:      testPeers(3),
+		Inputs:     map[PartyID]map[GateID]uint64{0: {}, 1: {}},
+		ExpOutputs: make(map[WireID]uint64),
+	}
+	for _, pair := range pairs {
+		x, y := b.Input(0), b.Input(1)
+		testCase.Inputs[0][GateID(x.ID)] = pair[0]
+		testCase.Inputs[1][GateID(y.ID)] = pair[1]
+		inverse := new(big.Int).ModInverse(new(big.Int).SetUint64(pair[1]), q)
+		testCase.ExpOutputs[b.Reveal(y.Inverse()).ID] = inverse.Uint64()
+		quotient := inverse.Mul(inverse, new(big.Int).SetUint64(pair[0]))
+		testCase.ExpOutputs[b.Reveal(x.Div(y)).ID] = quotient.Mod(quotient, q).Uint64()
+	}
+	testCase.Circuit = b.Circuit()
+
+	checkCircuit(t, testCase, 2+2)
+
+	testCase.Inputs[1][1] = 0
+	if _, err := EvaluateCircuit(testCase.Circuit, testCase.Inputs); err == nil {
+		t.Errorf("cleartext division by zero not reported")
+	}
+	var mutex sync.Mutex
+	var errs []error
+	runTrustedThirdParty(t, testCase, func(p *Protocol) error {
+		err := p.Run()
+		mutex.Lock()
+		errs = append(errs, err)
+		mutex.Unlock()
+		return nil
+	})
+	for _, err := range errs {
+		if _, isZero := err.(*ZeroInverseError); !isZero {
+			t.Errorf("division by zero not reported: %v", err)
+		}
+	}
+}
+
+// Raise values to exponents with various bit patterns, and check that the gates take ceil(log2(exponent)) rounds
+func TestPowCst(t *testing.T) {
+	exponents := []uint64{2, 3, 4, 5, 6, 7, 13, 16, 100, Params.T - 1, Params.T, Params.T + 1, 1<<40 + 3}
+	values := []uint64{0, 1, 2, 7, Params.T - 1, 12345}
+
+	b := NewCircuitBuilder()
+	testCase := &TestCircuit{
+		Peers:      testPeers(3),
+		Inputs:     map[PartyID]map[GateID]uint64{0: {}},
+		ExpOutputs: make(map[WireID]uint64),
+	}
+	triplets := 0
+	for _, value := range values {
+		x := b.Input(0)
+		testCase.Inputs[0][GateID(x.ID)] = value
+		for _, exponent := range exponents {
+			power := new(big.Int).Exp(new(big.Int).SetUint64(value), new(big.Int).SetUint64(exponent), q)
+			testCase.ExpOutputs[b.Reveal(x.Pow(exponent)).ID] = power.Uint64()
+			reduced := reduceExponent(exponent)
+			triplets += bits.Len64(reduced) - 1 + bits.OnesCount64(reduced) - 1
+		}
+	}
+	testCase.Circuit = b.Circuit()
+
+	if stats, err := ComputeStats(testCase.Circuit, 3); err != nil || stats.BeaverTriplets != triplets {
+		t.Errorf("%v triplets, expected %d (%v)", stats, triplets, err)
+	}
+
+	// x^(q-1) takes the most rounds, the other exponents above q-1 being reduced, e.g. 2^40+3 to 3
+	checkCircuit(t, testCase, 2+16)
+
+	for exponent, rounds := range map[uint64]int{2: 1, 3: 2, 4: 2, 5: 3, 8: 3, 9: 4, 100: 7, Params.T - 2: 16, Params.T: 0, 1<<40 + 3: 2} {
+		if depth := MultiplicativeDepths(Circuit{&PowCst{Exponent: exponent}})[0]; depth != rounds {
+			t.Errorf("x^%d computed in %d rounds, expected %d", exponent, depth, rounds)
+		}
+	}
+
+	// The exponents reduced to 0 and 1, which the builder never emits, are computed locally
+	testCase.Circuit = Circuit{&Input{Party: 0, Out: 0}, &PowCst{In: 0, Exponent: 0, Out: 1}, &PowCst{In: 0, Exponent: 1, Out: 2}, &PowCst{In: 0, Exponent: Params.T, Out: 3}, &Reveal{In: 1, Out: 4}, &Reveal{In: 2, Out: 5}, &Reveal{In: 3, Out: 6}}
+	testCase.Inputs = map[PartyID]map[GateID]uint64{0: {0: 12345}}
+	testCase.ExpOutputs = map[WireID]uint64{4: 1, 5: 12345, 6: 12345}
+	checkCircuit(t, testCase, 2)
+}
+
+// Compute inner products of long vectors, which take a single round whatever their length, and compare them with
+// the sums of element-wise products
+func TestDotProduct(t *testing.T) {
+	n := 50
+	b := NewCircuitBuilder()
+	x, y := b.VectorInput(0, n), b.VectorInput(1, n)
+	weights := make([]uint64, n)
+	for i := range weights {
+		weights[i] = uint64(i * i)
+	}
+	dot := b.Reveal(x.Dot(y))
+	dotCst := b.Reveal(x.DotConst(weights))
+	sum, sumCst := x.Mul(y).Elem(0), x.MulConst(weights[0]).Elem(0)
+	for i := 1; i < n; i++ {
+		sum = sum.Add(x.Elem(i).Mul(y.Elem(i)))
+		sumCst = sumCst.Add(x.Elem(i).MulConst(weights[i]))
+	}
+	sum, sumCst = b.Reveal(sum), b.Reveal(sumCst)
+
+	testCase := &TestCircuit{
+		Peers:  testPeers(3),
+		Inputs: map[PartyID]map[GateID]uint64{0: {}, 1: {}},
+	}
+	testCase.Circuit = b.Circuit()
+	for i := 0; i < n; i++ {
+		testCase.Inputs[0][GateID(x.ID)+GateID(i)] = rand.Uint64() % Params.T
+		testCase.Inputs[1][GateID(y.ID)+GateID(i)] = rand.Uint64() % Params.T
+	}
+	ce, err := EvaluateCircuit(testCase.Circuit, testCase.Inputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCase.ExpOutputs = ce.Outputs
+	if ce.Outputs[dot.ID] != ce.Outputs[sum.ID] || ce.Outputs[dotCst.ID] != ce.Outputs[sumCst.ID] {
+		t.Errorf("inner products %v differ from the sums of products", ce.Outputs)
+	}
+	checkCircuit(t, testCase, 3)
+
+	stats, err := ComputeStats(Circuit{&DotProduct{In1: vectorWires(x.ID, n), In2: vectorWires(y.ID, n), Out: 200}}, 3)
+	if err != nil || stats.BeaverTriplets != n || stats.SequentialRounds != 1 {
+		t.Errorf("inner product of length %d: %+v (%v)", n, stats, err)
+	}
+
+	for kind, op := range map[ValidationErrorKind]Operation{
+		MismatchedLength: &DotProductCst{In: []WireID{0, 1}, CstValues: []uint64{1}, Out: 2},
+		EmptyOperands:    DotProduct{Out: 2},
+	} {
+		invalid := Circuit{&VecInput{Party: 0, Out: 0, Len: 2}, op, &Reveal{In: 2, Out: 3}}
+		err = ValidateCircuit(invalid, testCase.Peers, nil)
+		if errs, ok := err.(ValidationErrors); !ok || len(errs) != 1 || errs[0].Kind != kind {
+			t.Errorf("%T: invalid operands not reported: %v", op, err)
+		}
+	}
+}
+
+// Multiply matrices whose triplets span several runs of the BFV protocol, and check that the product opens the
+// masked operands only
+func TestMatMul(t *testing.T) {
+	n, k, m := 20, 30, 15
+	b := NewCircuitBuilder()
+	x, y, z := b.VectorInput(0, n*k), b.VectorInput(1, k*m), b.VectorInput(1, m*m)
+	xy := x.MatMul(y, n, m)
+	b.RevealVector(xy)
+	b.RevealVector(xy.MatMul(z, n, m).AddConst(1))
+
+	testCase := &TestCircuit{
+		Peers:  testPeers(2),
+		Inputs: map[PartyID]map[GateID]uint64{0: {}, 1: {}},
+	}
+	testCase.Circuit = b.Circuit()
+	for party, v := range map[PartyID][]Vector{0: {x}, 1: {y, z}} {
+		for _, vector := range v {
+			for i := 0; i < vector.Len; i++ {
+				testCase.Inputs[party][GateID(vector.ID)+GateID(i)] = rand.Uint64() % Params.T
+			}
+		}
+	}
+	ce, err := EvaluateCircuit(testCase.Circuit, testCase.Inputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCase.ExpOutputs = ce.Outputs
+
+	stats, err := ComputeStats(testCase.Circuit, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slots := n*m*k + n*m*m; stats.MatrixTriplets != 2 || stats.BeaverTriplets != 0 || stats.HEBatches != (slots+1<<Params.LogN-1)>>Params.LogN {
+		t.Errorf("unexpected preprocessing: %+v", stats)
+	}
+	checkCircuit(t, testCase, 4)
+
+	// Matrix triplets generated with BFV, over two runs of the Beaver triplet protocol
+	localParties := make([]*LocalParty, 2)
+	for i := range testCase.Peers {
+		localParties[i], err = NewLocalParty(i, testCase.Peers)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	network := GetTestingTCPNetwork(localParties)
+	matrixTriplets := map[PartyID]map[WireID]MatrixTriplet{0: {}, 1: {}}
+	wg := new(sync.WaitGroup)
+	for i, lp := range localParties {
+		lp.BindNetwork(network[i])
+		wg.Add(1)
+		go func(lp *LocalParty) {
+			defer wg.Done()
+			ComputeMatrixTripletsHE(lp.NewBeaverProtocol(Params), matrixTriplets, testCase.Circuit)
+		}(lp)
+	}
+	wg.Wait()
+
+	if err := ValidateMatrixTriplets(testCase.Circuit, matrixTriplets[0]); err != nil {
+		t.Fatal(err)
+	}
+	open := func(shares0, shares1 []*big.Int) []*big.Int {
+		values := make([]*big.Int, len(shares0))
+		for i := range values {
+			values[i] = new(big.Int).Add(shares0[i], shares1[i])
+			values[i].Mod(values[i], q)
+		}
+		return values
+	}
+	for _, op := range testCase.Circuit {
+		if mmo, isMatMul := op.(*MatMul); isMatMul {
+			t0, t1 := matrixTriplets[0][mmo.Out], matrixTriplets[1][mmo.Out]
+			if !reflect.DeepEqual(open(t0.c, t1.c), matrixProduct(open(t0.a, t1.a), open(t0.b, t1.b), mmo.Rows, mmo.Inner, mmo.Cols)) {
+				t.Errorf("matrix triplet of wire %d: C differs from AB", mmo.Out)
+			}
+		}
+	}
+
+	// Two negative dimensions give a positive length, but are reported like a zero dimension
+	for _, dims := range [][3]int{{-2, 3, -2}, {2, 0, 2}} {
+		invalid := Circuit{&VecInput{Party: 0, Out: 0, Len: 6}, &MatMul{In1: 0, In2: 0, Out: 6, Rows: dims[0], Inner: dims[1], Cols: dims[2]}, &Reveal{In: 6, Out: 10}}
+		err = ValidateCircuit(invalid, testCase.Peers, nil)
+		if errs, ok := err.(ValidationErrors); !ok || len(errs) != 1 || errs[0].Kind != InvalidLength {
+			t.Errorf("dimensions %v not reported: %v", dims, err)
+		}
+	}
+}
+
+// Select values with valid conditions, with and without the check, and check that a condition which is not a bit
+// stops the evaluation of all the parties with a NotABitError when checked
+func TestSelect(t *testing.T) {
+	b := NewCircuitBuilder()
+	testCase := &TestCircuit{
+		Peers:      testPeers(3),
+		Inputs:     map[PartyID]map[GateID]uint64{0: {}, 1: {}, 2: {}},
+		ExpOutputs: make(map[WireID]uint64),
+	}
+	for _, cond := range []uint64{0, 1} {
+		for _, pair := range [][2]uint64{{5, 7}, {0, Params.T - 1}, {12345, 12345}} {
+			x, y, c := b.Input(0), b.Input(1), b.Input(2)
+			testCase.Inputs[0][GateID(x.ID)] = pair[0]
+			testCase.Inputs[1][GateID(y.ID)] = pair[1]
+			testCase.Inputs[2][GateID(c.ID)] = cond
+			testCase.ExpOutputs[b.Reveal(c.Select(x, y)).ID] = pair[1-cond]
+			testCase.ExpOutputs[b.Reveal(c.SelectChecked(x, y)).ID] = pair[1-cond]
+		}
+	}
+	testCase.Circuit = b.Circuit()
+	checkCircuit(t, testCase, 2+2)
+
+	// A condition of 2 is caught by the checked selection of the first case
+	testCase.Inputs[2][2] = 2
+	if _, err := EvaluateCircuit(testCase.Circuit, testCase.Inputs); err == nil {
+		t.Errorf("cleartext condition which is not a bit not reported")
+	}
+	var mutex sync.Mutex
+	var errs []error
+	runTrustedThirdParty(t, testCase, func(p *Protocol) error {
+		err := p.Run()
+		mutex.Lock()
+		errs = append(errs, err)
+		mutex.Unlock()
+		return nil
+	})
+	for _, err := range errs {
+		if _, isNotABit := err.(*NotABitError); !isNotABit {
+			t.Errorf("condition which is not a bit not reported: %v", err)
+		}
+	}
+}
+
+// Add 64 pairs of 8-bit numbers in parallel with a bit-sliced ripple-carry adder, whose wire i holds the bit i of the
+// 64 numbers, and check the triplets of the local dealer and the validation of the sharings
+func TestBoolean(t *testing.T) {
+	bits := 8
+	b := NewCircuitBuilder()
+	x, y := make([]BoolWire, bits), make([]BoolWire, bits)
+	for i := range x {
+		x[i], y[i] = b.BoolInput(0), b.BoolInput(1)
+	}
+	sum := make([]BoolWire, bits+1)
+	sum[0] = b.RevealBool(x[0].Xor(y[0]))
+	carry := x[0].And(y[0])
+	for i := 1; i < bits; i++ {
+		xy := x[i].Xor(y[i])
+		sum[i] = b.RevealBool(xy.Xor(carry))
+		carry = x[i].And(y[i]).Xor(carry.And(xy))
+	}
+	sum[bits] = b.RevealBool(carry)
+
+	testCase := &TestCircuit{
+		Peers:      testPeers(3),
+		Inputs:     map[PartyID]map[GateID]uint64{0: {}, 1: {}, 2: {}},
+		ExpOutputs: make(map[WireID]uint64),
+		Circuit:    b.Circuit(),
+	}
+	for lane := uint(0); lane < 64; lane++ {
+		a, c := rand.Uint64()%(1<<uint(bits)), rand.Uint64()%(1<<uint(bits))
+		for i := range x {
+			testCase.Inputs[0][GateID(x[i].ID)] |= (a >> uint(i) & 1) << lane
+			testCase.Inputs[1][GateID(y[i].ID)] |= (c >> uint(i) & 1) << lane
+		}
+		for i, w := range sum {
+			testCase.ExpOutputs[w.ID] |= ((a + c) >> uint(i) & 1) << lane
+		}
+	}
+
+	// One round for the inputs, then one per carry and one for the last reveal
+	checkCircuit(t, testCase, uint64(1+bits+1))
+
+	stats, err := ComputeStats(testCase.Circuit, 3)
+	if err != nil || stats.BinaryTriplets != 2*bits-1 || stats.BeaverTriplets != 0 {
+		t.Errorf("unexpected preprocessing: %+v (%v)", stats, err)
+	}
+
+	// The parties derive the shares of the same triplets from the common seed
+	dealer := LocalBinaryDealer{Seed: 7}
+	shares := make([]map[WireID]BinaryTriplet, 3)
+	for i := range shares {
+		shares[i] = dealer.Triplets(testCase.Circuit, PartyID(i), len(shares))
+	}
+	if err := ValidateBinaryTriplets(testCase.Circuit, shares[0]); err != nil {
+		t.Fatal(err)
+	}
+	for w := range shares[0] {
+		a, b, c := shares[0][w].a^shares[1][w].a^shares[2][w].a, shares[0][w].b^shares[1][w].b^shares[2][w].b, shares[0][w].c^shares[1][w].c^shares[2][w].c
+		if c != a&b {
+			t.Errorf("binary triplet of wire %d: c differs from a AND b", w)
+		}
+	}
+
+	err = ValidateBinaryTriplets(testCase.Circuit, nil)
+	if errs, ok := err.(ValidationErrors); !ok || len(errs) != 2*bits-1 || errs[0].Kind != MissingBinaryTriplet {
+		t.Errorf("missing binary triplets not reported: %v", err)
+	}
+	invalid := Circuit{&Input{Party: 0, Out: 0}, &BoolInput{Party: 1, Out: 1}, &And{In1: 0, In2: 1, Out: 2}, &AddCst{In: 1, CstValue: 1, Out: 3}, &BoolReveal{In: 2, Out: 4}}
+	err = ValidateCircuit(invalid, testCase.Peers, nil)
+	if errs, ok := err.(ValidationErrors); !ok || len(errs) != 2 || errs[0].Kind != MismatchedSharing || errs[0].Wire != 0 || errs[1].Wire != 1 {
+		t.Errorf("mixed sharings not reported: %v", err)
+	}
+}
+
+// Compose a circuit from several instances of sub-circuits and evaluate it
+func TestSubCircuit(t *testing.T) {
+	poly := PolynomialSubCircuit([]uint64{6, 6, 3, 1})
+
+	b := NewCircuitBuilder()
+	x, y, z := b.Input(0), b.Input(1), b.Input(2)
+	s := b.Call(SumSubCircuit(2), x, y)[0].Sub(z)
+	f := b.Reveal(b.Call(poly, s)[0])
+	g := b.Reveal(b.Call(poly, x)[0])
+
+	testCase := Circuit10
+	testCase.Circuit = b.Circuit()
+	testCase.ExpOutputs = map[WireID]uint64{f.ID: 538, g.ID: 1032}
+	if err := ValidateCircuit(testCase.Circuit, testCase.Peers, nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range runTrustedThirdParty(t, &testCase, (*Protocol).Run) {
+		checkOutputs(t, &testCase, p)
+	}
+
+	if _, _, _, err := poly.Instantiate([]WireID{0, 1}, 10); err == nil {
+		t.Errorf("wrong number of arguments should be reported")
+	}
+
+	instance, _, wires, err := poly.Instantiate([]WireID{4}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	triplets := make(map[WireID]BeaverTriplet)
+	for _, op := range poly.Circuit {
+		if op.IsMult() {
+			triplets[op.Output()] = op.BeaverTriplet(2)[0]
+		}
+	}
+	remapped := RemapBeaverTriplets(triplets, wires)
+	for _, op := range instance {
+		if _, exists := remapped[op.Output()]; exists != op.IsMult() {
+			t.Errorf("triplet of wire %d: %t, expected %t", op.Output(), exists, op.IsMult())
+		}
+	}
+}
+
+// Re-express the circuits of test_circuits.go with the builder and check that the same circuits are produced
+func TestCircuitBuilder(t *testing.T) {
+	builders := map[*TestCircuit]func(b *CircuitBuilder){
+		&Circuit1: func(b *CircuitBuilder) {
+			x, y, z := b.Input(0), b.Input(1), b.Input(2)
+			b.Reveal(z.Add(x.Add(y)))
+		},
+		&Circuit2: func(b *CircuitBuilder) {
+			x, y := b.Input(0), b.Input(1)
+			b.Reveal(x.Sub(y))
+		},
+		&Circuit3: func(b *CircuitBuilder) {
+			x, y, z := b.Input(0), b.Input(1), b.Input(2)
+			b.Reveal(z.Add(x.Add(y)).MulConst(5))
+		},
+		&Circuit4: func(b *CircuitBuilder) {
+			x, y, z := b.Input(0), b.Input(1), b.Input(2)
+			b.Reveal(z.Add(x.Add(y)).AddConst(7))
+		},
+		&Circuit5: func(b *CircuitBuilder) {
+			x, y, z := b.Input(0), b.Input(1), b.Input(2)
+			b.Reveal(x.MulConst(8).Add(y).Sub(z).AddConst(8))
+		},
+		&Circuit6: func(b *CircuitBuilder) {
+			w, x, y, z := b.Input(0), b.Input(1), b.Input(2), b.Input(3)
+			s1, s2 := w.Add(x), y.Add(z)
+			b.Reveal(s1.Add(s2))
+		},
+		&Circuit7: func(b *CircuitBuilder) {
+			x, y, z := b.Input(0), b.Input(1), b.Input(2)
+			xy, yz, xz := x.Mul(y), y.Mul(z), x.Mul(z)
+			b.Reveal(xz.Add(xy.Add(yz)))
+		},
+		&Circuit8: func(b *CircuitBuilder) {
+			a, bb, c, d, e := b.Input(0), b.Input(1), b.Input(2), b.Input(3), b.Input(4)
+			a42, b4, de := a.AddConst(42), bb.MulConst(4), d.Add(e)
+			b.Reveal(de.Mul(a42.Add(b4).Sub(c)))
+		},
+		&Circuit9: func(b *CircuitBuilder) {
+			x, y := b.Input(0), b.Input(1)
+			b.Reveal(x.Mul(y))
+		},
+		&Circuit10: func(b *CircuitBuilder) {
+			x, y, z := b.Input(0), b.Input(1), b.Input(2)
+			s := x.Add(y).Sub(z)
+			s2 := s.Mul(s)
+			s3 := s.Mul(s2)
+			b.Reveal(s3.Add(s.MulConst(6).Add(s2.MulConst(3))).AddConst(6))
+		},
+		&Circuit11: func(b *CircuitBuilder) {
+			x, y, z := b.Input(0), b.Input(1), b.Input(2)
+			b.Reveal(x.Add(y).Add(z))
+			xy := x.Mul(y)
+			b.Reveal(xy)
+			b.Reveal(xy.Mul(z))
+		},
+		&Circuit12: func(b *CircuitBuilder) {
+			a0, a1, a2, b0, b1 := b.Input(0), b.Input(0), b.Input(0), b.Input(1), b.Input(1)
+			b.Reveal(a0.Add(a1).Add(a2).Add(b0).Add(b1))
+		},
+		&Circuit13: func(b *CircuitBuilder) {
+			x, y, z := b.Input(0), b.Input(1), b.Input(2)
+			sum := x.Add(y).Add(z)
+			b.RevealTo(sum, 2)
+			b.RevealTo(x.Mul(y), 0)
+			b.Reveal(sum.MulConst(2))
+		},
+		&Circuit14: func(b *CircuitBuilder) {
+			x, y := b.VectorInput(0, 4), b.VectorInput(1, 4)
+			xy := x.Mul(y)
+			b.RevealVector(xy.Add(x.MulConst(3)))
+			b.Reveal(xy.Elem(0).Add(xy.Elem(1)).Add(xy.Elem(2)).Add(xy.Elem(3)))
+		},
+		&Circuit15: func(b *CircuitBuilder) {
+			x, y, z := b.Input(0), b.Input(1), b.Input(2)
+			lt := x.LessThan(y)
+			b.Reveal(x.Add(lt.Mul(y.Sub(x))).Max(z))
+			b.Reveal(lt)
+		},
+		&Circuit16: func(b *CircuitBuilder) {
+			x, y, z := b.Input(0), b.Input(1), b.Input(2)
+			b.Reveal(x.Equal(y).Add(x.Equal(z)).Add(y.Equal(z)))
+		},
+		&Circuit17: func(b *CircuitBuilder) {
+			x, y := b.Input(0), b.Input(1)
+			bits := x.Add(y).Bits()
+			b.RevealVector(bits)
+			weight := bits.Elem(0)
+			for i := 1; i < bits.Len; i++ {
+				weight = weight.Add(bits.Elem(i))
+			}
+			b.Reveal(weight)
+		},
+		&Circuit18: func(b *CircuitBuilder) {
+			fp := FixedPoint{2}
+			x1, x2, w1, w2 := b.Input(0), b.Input(0), b.Input(1), b.Input(1)
+			b.Reveal(x1.MulFixed(w1, fp).Add(x2.MulFixed(w2, fp)))
+		},
+		&Circuit19: func(b *CircuitBuilder) {
+			a, c, n := b.Input(0), b.Input(1), b.Input(2)
+			b.Reveal(a.Add(c).Div(n))
+		},
+		&Circuit20: func(b *CircuitBuilder) {
+			x, y, z := b.Input(0), b.Input(1), b.Input(2)
+			s := x.Add(y).Sub(z)
+			b.Reveal(s.MulConst(6).AddConst(6).Add(s.Pow(2).MulConst(3)).Add(s.Pow(3)))
+		},
+		&Circuit21: func(b *CircuitBuilder) {
+			x, w := b.VectorInput(0, 4), b.VectorInput(1, 4)
+			b.Reveal(x.Dot(w))
+			b.Reveal(x.DotConst([]uint64{1, 2, 3, 4}))
+		},
+		&Circuit22: func(b *CircuitBuilder) {
+			x, y := b.VectorInput(0, 6), b.VectorInput(1, 6)
+			b.RevealVector(x.MatMul(y, 2, 2))
+		},
+		&Circuit23: func(b *CircuitBuilder) {
+			x, y, c := b.Input(0), b.Input(1), b.Input(2)
+			b.Reveal(c.SelectChecked(x, y))
+			b.Reveal(x.LessThan(y).Select(y, x))
+		},
+		&Circuit24: func(b *CircuitBuilder) {
+			x, y, z := b.BoolInput(0), b.BoolInput(1), b.BoolInput(2)
+			b.RevealBool(x.And(y).Xor(z.Not()))
+			b.RevealBool(y.Or(z))
+		},
+	}
+
+	for i, testCase := range TestCircuits {
+		build, exists := builders[testCase]
+		if !exists {
+			t.Errorf("circuit%d: not re-expressed with the builder", i+1)
+			continue
+		}
+		b := NewCircuitBuilder()
+		build(b)
+		if circuit := b.Circuit(); !reflect.DeepEqual(circuit, testCase.Circuit) {
+			t.Errorf("circuit%d: builder produced a different circuit", i+1)
+		}
+	}
 }
 
 func BenchmarkPreProcessOneMult3P(b *testing.B) {
@@ -155,7 +1658,7 @@ func BenchmarkPreProcessOneMult3P(b *testing.B) {
 		cir := make([]Operation, 0)
 		port := PartyID(1025)
 		for i := PartyID(0); i < PartyID(n+1); i++ {
-			peers[i] = "localhost:" + string(i+port)
+			peers[i] = fmt.Sprintf("localhost:%d", i+port)
 			inputs[i] = map[GateID]uint64{GateID(i): uint64(7)}
 			cir = append(cir, &Input{
 				Party: i,
@@ -173,7 +1676,7 @@ func BenchmarkPreProcessOneMult3P(b *testing.B) {
 			In:  WireID(n + 1),
 			Out: WireID(n + 2),
 		})
-		circuit.ExpOutput = 49
+		circuit.ExpOutputs = map[WireID]uint64{WireID(n + 2): 49}
 
 		benchmarks[n] = circuit
 
@@ -235,7 +1738,7 @@ func BenchmarkPreProcessOneMultHE(b *testing.B) {
 			In:  WireID(n + 1),
 			Out: WireID(n + 2),
 		})
-		circuit.ExpOutput = 49
+		circuit.ExpOutputs = map[WireID]uint64{WireID(n + 2): 49}
 
 		bs[n].circuit = circuit
 
@@ -409,8 +1912,7 @@ func BenchmarkOperations(b *testing.B) {
 				0: {0: 11},
 				1: {1: 8},
 			},
-			Circuit:   circuits[i],
-			ExpOutput: 0,
+			Circuit: circuits[i],
 		}
 	}
 
@@ -453,15 +1955,19 @@ func BenchmarkOperations(b *testing.B) {
 				lp.BindNetwork(network[i])
 			}
 
+			randomBits := DealRandomBits(testCase.Circuit, N)
 			for i, lp := range localParties {
-				protocol[i] = lp.NewProtocol(testCase.Inputs[lp.ID][GateID(i)], testCase.Circuit, beaverTriplets[lp.ID])
+				protocol[i] = lp.NewProtocol(testCase.Inputs[lp.ID], testCase.Circuit, beaverTriplets[lp.ID])
+				protocol[i].RandomBits = randomBits[lp.ID]
 			}
 			b.ResetTimer()
 			for _, p := range protocol {
 				p.Add(1)
 				go func(protocol *Protocol) {
 					defer protocol.Done()
-					protocol.Run()
+					if err := protocol.Run(); err != nil {
+						b.Error(err)
+					}
 				}(p)
 			}
 
@@ -470,3 +1976,95 @@ func BenchmarkOperations(b *testing.B) {
 		})
 	}
 }
+
+// Compare the gate by gate evaluation with the evaluation batched by layer, on circuits with independent multiplications
+func BenchmarkOnlinePhase(b *testing.B) {
+	wide := TestCircuit{
+		Peers: map[PartyID]string{
+			0: "localhost:6660",
+			1: "localhost:6661",
+			2: "localhost:6662",
+		},
+		Inputs: map[PartyID]map[GateID]uint64{
+			0: {0: 7},
+			1: {1: 3},
+			2: {2: 14},
+		},
+		Circuit: Circuit{&Input{0, 0}, &Input{1, 1}, &Input{2, 2}},
+	}
+	// 32 independent products of the inputs, summed up
+	sum := WireID(3)
+	wide.Circuit = append(wide.Circuit, &Add{0, 1, sum})
+	for i := WireID(0); i < 32; i++ {
+		prod := 4 + 2*i
+		wide.Circuit = append(wide.Circuit, &Mult{i % 3, (i + 1) % 3, prod}, &Add{sum, prod, prod + 1})
+		sum = prod + 1
+	}
+	wide.Circuit = append(wide.Circuit, &Reveal{sum, sum + 1})
+
+	names := []string{"Circuit7", "Circuit10", "32Mults"}
+	benchmarks := map[string]*TestCircuit{
+		"Circuit7":  &Circuit7,
+		"Circuit10": &Circuit10,
+		"32Mults":   &wide,
+	}
+	runs := map[string]func(*Protocol) error{
+		"sequential": (*Protocol).RunSequential,
+		"batched":    (*Protocol).Run,
+	}
+
+	for _, name := range names {
+		testCase := benchmarks[name]
+		N := len(testCase.Peers)
+		localParties := make([]*LocalParty, N, N)
+		for i := range testCase.Peers {
+			var err error
+			localParties[i], err = NewLocalParty(i, testCase.Peers)
+			if err != nil {
+				b.Errorf("creation of new local party failed")
+			}
+		}
+
+		network := GetTestingTCPNetwork(localParties)
+		for i, lp := range localParties {
+			lp.BindNetwork(network[i])
+		}
+
+		beaverTriplets := make(map[PartyID]map[WireID]BeaverTriplet)
+		for peerID := range testCase.Peers {
+			beaverTriplets[peerID] = make(map[WireID]BeaverTriplet)
+		}
+		for _, op := range testCase.Circuit {
+			if triplets := op.BeaverTriplet(N); triplets != nil {
+				for id, triplet := range triplets {
+					beaverTriplets[PartyID(id)][op.Output()] = triplet
+				}
+			}
+		}
+
+		for _, mode := range []string{"sequential", "batched"} {
+			run := runs[mode]
+			b.Run(fmt.Sprintf("%s/%s", name, mode), func(b *testing.B) {
+				var rounds uint64
+				for n := 0; n < b.N; n++ {
+					wg := new(sync.WaitGroup)
+					for _, lp := range localParties {
+						p := lp.NewProtocol(testCase.Inputs[lp.ID], testCase.Circuit, beaverTriplets[lp.ID])
+						wg.Add(1)
+						go func(p *Protocol) {
+							defer wg.Done()
+							if err := run(p); err != nil {
+								b.Error(err)
+							}
+							if p.ID == 0 {
+								rounds = p.Rounds
+							}
+						}(p)
+					}
+					wg.Wait()
+				}
+				b.ReportMetric(float64(rounds), "rounds")
+			})
+		}
+	}
+}
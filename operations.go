@@ -19,13 +19,17 @@ type BeaverTriplet struct {
 
 type Operation interface {
 	Output() WireID
-	Eval(*Protocol)                    // computes the operation of the wire and stores the result in the WireOutput map
-	BeaverTriplet(int) []BeaverTriplet // If necessary, returns the sahres for a  Beaver triplet, otherwise nil
-	IsMult() bool                      // returns true if and only if the gate is a multiplication
+	Inputs() []WireID                    // returns the wires read by the operation
+	Eval(*Protocol)                      // computes the operation of the wire and stores the result in the WireOutput map
+	EvalClear(*ClearEvaluation)          // computes the operation in clear, without sharing nor communication
+	Remap(func(WireID) WireID) Operation // returns a copy of the operation where each wire w is replaced by f(w)
+	BeaverTriplet(int) []BeaverTriplet   // If necessary, returns the sahres for a  Beaver triplet, otherwise nil
+	IsMult() bool                        // returns true if and only if the gate is a multiplication
 }
 
-// Given an input, split it between the peers and send them their share
-func (io Input) generateShares(cep *Protocol) {
+// Given an input, split it between the peers: keep our share and return the share of each peer
+func (io Input) generateShares(cep *Protocol) map[PartyID][]*big.Int {
+	shares := make(map[PartyID][]*big.Int, len(cep.Peers))
 	sum := big.NewInt(0)
 	for _, peer := range cep.Peers {
 		if peer.ID != cep.ID {
@@ -34,12 +38,13 @@ func (io Input) generateShares(cep *Protocol) {
 			check(err)
 
 			sum.Add(sum, share)
-			peer.SendingChan <- Message{MPCMessage: &MPCMessage{io.Out, share.Uint64()}}
+			shares[peer.ID] = []*big.Int{share}
 		}
 	}
-	s := big.NewInt(int64(cep.Input))
+	s := new(big.Int).SetUint64(cep.Inputs[GateID(io.Out)])
 	cep.WireOutput[io.Out] = new(big.Int).Sub(s, sum)
 	cep.WireOutput[io.Out].Mod(cep.WireOutput[io.Out], q)
+	return shares
 }
 
 type Input struct {
@@ -51,10 +56,25 @@ func (io Input) Output() WireID {
 	return io.Out
 }
 
-// If the input is our, split it using the method 'generateShares', otherwise receive our share from the concerned peer
+func (io Input) Inputs() []WireID {
+	return nil
+}
+
+func (io Input) Remap(f func(WireID) WireID) Operation {
+	return &Input{Party: io.Party, Out: f(io.Out)}
+}
+
+func (io Input) Owner() PartyID {
+	return io.Party
+}
+
+// If the input is our, split it using the method 'generateShares' and send them their share, otherwise receive our share from the concerned peer
 func (io Input) Eval(cep *Protocol) {
+	cep.Rounds++
 	if io.Party == cep.ID {
-		io.generateShares(cep)
+		for id, share := range io.generateShares(cep) {
+			cep.Peers[id].SendingChan <- Message{MPCMessage: &MPCMessage{io.Out, share[0].Uint64()}}
+		}
 	} else {
 		m := <-cep.Peers[io.Party].ReceiveChan
 		if m.MPCMessage == nil {
@@ -64,6 +84,10 @@ func (io Input) Eval(cep *Protocol) {
 	}
 }
 
+func (io Input) EvalClear(ce *ClearEvaluation) {
+	ce.Wires[io.Out] = new(big.Int).Mod(new(big.Int).SetUint64(ce.Inputs[io.Party][GateID(io.Out)]), q)
+}
+
 func (io Input) BeaverTriplet(count int) []BeaverTriplet {
 	return nil
 }
@@ -86,10 +110,23 @@ func (ao Add) Output() WireID {
 	return ao.Out
 }
 
+func (ao Add) Inputs() []WireID {
+	return []WireID{ao.In1, ao.In2}
+}
+
+func (ao Add) Remap(f func(WireID) WireID) Operation {
+	return &Add{In1: f(ao.In1), In2: f(ao.In2), Out: f(ao.Out)}
+}
+
 func (ao Add) Eval(cep *Protocol) {
 	cep.WireOutput[ao.Out] = new(big.Int).Add(cep.WireOutput[ao.In1], cep.WireOutput[ao.In2])
 }
 
+func (ao Add) EvalClear(ce *ClearEvaluation) {
+	ce.Wires[ao.Out] = new(big.Int).Add(ce.Wires[ao.In1], ce.Wires[ao.In2])
+	ce.Wires[ao.Out].Mod(ce.Wires[ao.Out], q)
+}
+
 func (ao Add) BeaverTriplet(count int) []BeaverTriplet {
 	return nil
 }
@@ -108,13 +145,26 @@ func (aco AddCst) Output() WireID {
 	return aco.Out
 }
 
+func (aco AddCst) Inputs() []WireID {
+	return []WireID{aco.In}
+}
+
+func (aco AddCst) Remap(f func(WireID) WireID) Operation {
+	return &AddCst{In: f(aco.In), CstValue: aco.CstValue, Out: f(aco.Out)}
+}
+
 func (aco AddCst) Eval(cep *Protocol) {
-	cep.WireOutput[aco.Out] = big.NewInt(cep.WireOutput[aco.In].Int64())
+	cep.WireOutput[aco.Out] = new(big.Int).Set(cep.WireOutput[aco.In])
 	if cep.ID == 0 {
 		cep.WireOutput[aco.Out].Add(cep.WireOutput[aco.Out], big.NewInt(int64(aco.CstValue)))
 	}
 }
 
+func (aco AddCst) EvalClear(ce *ClearEvaluation) {
+	ce.Wires[aco.Out] = new(big.Int).Add(ce.Wires[aco.In], new(big.Int).SetUint64(aco.CstValue))
+	ce.Wires[aco.Out].Mod(ce.Wires[aco.Out], q)
+}
+
 func (aco AddCst) BeaverTriplet(count int) []BeaverTriplet {
 	return nil
 }
@@ -133,10 +183,23 @@ func (so Sub) Output() WireID {
 	return so.Out
 }
 
+func (so Sub) Inputs() []WireID {
+	return []WireID{so.In1, so.In2}
+}
+
+func (so Sub) Remap(f func(WireID) WireID) Operation {
+	return &Sub{In1: f(so.In1), In2: f(so.In2), Out: f(so.Out)}
+}
+
 func (so Sub) Eval(cep *Protocol) {
 	cep.WireOutput[so.Out] = new(big.Int).Sub(cep.WireOutput[so.In1], cep.WireOutput[so.In2])
 }
 
+func (so Sub) EvalClear(ce *ClearEvaluation) {
+	ce.Wires[so.Out] = new(big.Int).Sub(ce.Wires[so.In1], ce.Wires[so.In2])
+	ce.Wires[so.Out].Mod(ce.Wires[so.Out], q)
+}
+
 func (so Sub) BeaverTriplet(count int) []BeaverTriplet {
 	return nil
 }
@@ -155,18 +218,19 @@ func (mo Mult) Output() WireID {
 	return mo.Out
 }
 
+func (mo Mult) Inputs() []WireID {
+	return []WireID{mo.In1, mo.In2}
+}
+
+func (mo Mult) Remap(f func(WireID) WireID) Operation {
+	return &Mult{In1: f(mo.In1), In2: f(mo.In2), Out: f(mo.Out)}
+}
+
 // Executes a multiplication using the Beaver triplet that were already generated
 func (mo Mult) Eval(cep *Protocol) {
-	x := cep.WireOutput[mo.In1]
-	y := cep.WireOutput[mo.In2]
-	a := cep.BeaverTriplets[mo.Output()].a
-	b := cep.BeaverTriplets[mo.Output()].b
-	c := cep.BeaverTriplets[mo.Output()].c
-
-	X_a := big.NewInt(0)
-	X_a.Sub(x, a).Mod(X_a, q)
-	Y_b := big.NewInt(0)
-	Y_b.Sub(y, b).Mod(Y_b, q)
+	shares := mo.Shares(cep)
+	X_a := shares[0]
+	Y_b := shares[1]
 
 	for _, peer := range cep.Peers {
 		if peer.ID != cep.ID {
@@ -197,6 +261,38 @@ func (mo Mult) Eval(cep *Protocol) {
 		}
 	}
 
+	cep.Rounds++
+	mo.Open(cep, []*big.Int{X_a, Y_b})
+}
+
+func (mo Mult) EvalClear(ce *ClearEvaluation) {
+	ce.Wires[mo.Out] = new(big.Int).Mul(ce.Wires[mo.In1], ce.Wires[mo.In2])
+	ce.Wires[mo.Out].Mod(ce.Wires[mo.Out], q)
+}
+
+// Returns our shares of x-a and y-b, where (a, b, c) is the Beaver triplet of the gate
+func (mo Mult) Shares(cep *Protocol) []*big.Int {
+	x := cep.WireOutput[mo.In1]
+	y := cep.WireOutput[mo.In2]
+	a := cep.BeaverTriplets[mo.Output()].a
+	b := cep.BeaverTriplets[mo.Output()].b
+
+	X_a := big.NewInt(0)
+	X_a.Sub(x, a).Mod(X_a, q)
+	Y_b := big.NewInt(0)
+	Y_b.Sub(y, b).Mod(Y_b, q)
+
+	return []*big.Int{X_a, Y_b}
+}
+
+// Computes our share of x*y from the opened values x-a and y-b
+func (mo Mult) Open(cep *Protocol, opened []*big.Int) {
+	x := cep.WireOutput[mo.In1]
+	y := cep.WireOutput[mo.In2]
+	c := cep.BeaverTriplets[mo.Output()].c
+	X_a := opened[0]
+	Y_b := opened[1]
+
 	z := big.NewInt(0)
 	z.Add(z, c)
 
@@ -214,6 +310,10 @@ func (mo Mult) Eval(cep *Protocol) {
 	cep.WireOutput[mo.Output()] = z
 }
 
+func (mo Mult) OpenCount() int {
+	return 2
+}
+
 func (mo Mult) BeaverTriplet(count int) []BeaverTriplet {
 	triplets := make([]BeaverTriplet, count)
 
@@ -270,10 +370,23 @@ func (mco MultCst) Output() WireID {
 	return mco.Out
 }
 
+func (mco MultCst) Inputs() []WireID {
+	return []WireID{mco.In}
+}
+
+func (mco MultCst) Remap(f func(WireID) WireID) Operation {
+	return &MultCst{In: f(mco.In), CstValue: mco.CstValue, Out: f(mco.Out)}
+}
+
 func (mco MultCst) Eval(cep *Protocol) {
 	cep.WireOutput[mco.Out] = new(big.Int).Mul(cep.WireOutput[mco.In], big.NewInt(int64(mco.CstValue)))
 }
 
+func (mco MultCst) EvalClear(ce *ClearEvaluation) {
+	ce.Wires[mco.Out] = new(big.Int).Mul(ce.Wires[mco.In], new(big.Int).SetUint64(mco.CstValue))
+	ce.Wires[mco.Out].Mod(ce.Wires[mco.Out], q)
+}
+
 func (mco MultCst) BeaverTriplet(count int) []BeaverTriplet {
 	return nil
 }
@@ -291,10 +404,17 @@ func (ro Reveal) Output() WireID {
 	return ro.Out
 }
 
+func (ro Reveal) Inputs() []WireID {
+	return []WireID{ro.In}
+}
+
+func (ro Reveal) Remap(f func(WireID) WireID) Operation {
+	return &Reveal{In: f(ro.In), Out: f(ro.Out)}
+}
+
 // Reveal the output by adding all the shares together
 func (ro Reveal) Eval(cep *Protocol) {
-	inputShare := cep.WireOutput[ro.In]
-	inputShare.Mod(inputShare, q)
+	inputShare := ro.Shares(cep)[0]
 
 	for _, peer := range cep.Peers {
 		if peer.ID != cep.ID {
@@ -319,11 +439,113 @@ func (ro Reveal) Eval(cep *Protocol) {
 		}
 	}
 
-	sum.Mod(sum, q)
+	cep.Rounds++
+	ro.Open(cep, []*big.Int{sum})
+}
+
+func (ro Reveal) EvalClear(ce *ClearEvaluation) {
+	ce.Wires[ro.Out] = new(big.Int).Set(ce.Wires[ro.In])
+	ce.Outputs[ro.Out] = ce.Wires[ro.Out].Uint64()
+}
+
+// Returns our share of the revealed wire
+func (ro Reveal) Shares(cep *Protocol) []*big.Int {
+	return []*big.Int{new(big.Int).Mod(cep.WireOutput[ro.In], q)}
+}
+
+// Stores the revealed value in the wire and in the outputs of the protocol
+func (ro Reveal) Open(cep *Protocol, opened []*big.Int) {
+	cep.WireOutput[ro.Output()] = new(big.Int).Mod(opened[0], q)
+	cep.Outputs[ro.Output()] = cep.WireOutput[ro.Output()].Uint64()
+}
 
-	cep.WireOutput[ro.Output()] = sum
+func (ro Reveal) OpenCount() int {
+	return 1
 }
 
 func (ro Reveal) BeaverTriplet(count int) []BeaverTriplet {
 	return nil
 }
+
+type RevealTo struct {
+	In    WireID
+	Out   WireID
+	Party PartyID
+}
+
+func (ro RevealTo) IsMult() bool {
+	return false
+}
+
+func (ro RevealTo) Output() WireID {
+	return ro.Out
+}
+
+func (ro RevealTo) Inputs() []WireID {
+	return []WireID{ro.In}
+}
+
+func (ro RevealTo) Remap(f func(WireID) WireID) Operation {
+	return &RevealTo{In: f(ro.In), Out: f(ro.Out), Party: ro.Party}
+}
+
+func (ro RevealTo) Recipient() PartyID {
+	return ro.Party
+}
+
+// Reveal the output to the recipient only: the other parties send it their share, and do not learn anything
+func (ro RevealTo) Eval(cep *Protocol) {
+	inputShare := ro.Shares(cep)[0]
+
+	if cep.ID != ro.Party {
+		cep.Peers[ro.Party].SendingChan <- Message{MPCMessage: &MPCMessage{
+			Out:   ro.Output(),
+			Value: inputShare.Uint64(),
+		}}
+		cep.Rounds++
+		return
+	}
+
+	sum := big.NewInt(0)
+	sum.Add(sum, inputShare)
+
+	for _, peer := range cep.Peers {
+		if peer.ID != cep.ID {
+			m := <-peer.ReceiveChan
+			if m.MPCMessage == nil {
+				check(errors.New("BeaverMessage received instead of MPCMessage"))
+			}
+			sum.Add(sum, big.NewInt(int64(m.MPCMessage.Value)))
+		}
+	}
+
+	cep.Rounds++
+	ro.Open(cep, []*big.Int{sum})
+}
+
+func (ro RevealTo) EvalClear(ce *ClearEvaluation) {
+	ce.Wires[ro.Out] = new(big.Int).Set(ce.Wires[ro.In])
+	ce.PrivateOutputs[ro.Out] = ce.Wires[ro.Out].Uint64()
+}
+
+// Returns our share of the revealed wire
+func (ro RevealTo) Shares(cep *Protocol) []*big.Int {
+	return []*big.Int{new(big.Int).Mod(cep.WireOutput[ro.In], q)}
+}
+
+// Stores the revealed value in the wire and in the private outputs of the protocol, if we are the recipient
+func (ro RevealTo) Open(cep *Protocol, opened []*big.Int) {
+	if cep.ID != ro.Party {
+		return
+	}
+	cep.WireOutput[ro.Output()] = new(big.Int).Mod(opened[0], q)
+	cep.PrivateOutputs[ro.Output()] = cep.WireOutput[ro.Output()].Uint64()
+}
+
+func (ro RevealTo) OpenCount() int {
+	return 1
+}
+
+func (ro RevealTo) BeaverTriplet(count int) []BeaverTriplet {
+	return nil
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+// Returns an equivalent circuit consuming fewer Beaver triplets and rounds. In a single pass, the optimizer tracks the
+// wires whose value is known at compile time (e.g. x-x, or a constant added to such a wire), rewrites a Mult by such a
+// wire into a MultCst and an Add or Sub into an AddCst, merges chained AddCst and MultCst, drops those adding 0 or
+// multiplying by 1, and merges the gates computing the same operation on the same wires. It then removes the gates
+// that don't reach any reveal gate. Input and reveal gates are always kept and wires are not renumbered, so that the
+// inputs and the expected outputs of the circuit remain valid. The wires read by vector operations are never
+// replaced, since these only remap the first wire of their input vectors. The circuit must be valid (see
+// ValidateCircuit)
+func Optimize(circuit Circuit) Circuit {
+	o := &optimizer{
+		alias:     make(map[WireID]WireID),
+		constants: make(map[WireID]uint64),
+		producer:  make(map[WireID]Operation),
+		computed:  make(map[string]Operation),
+		pinned:    make(map[WireID]bool),
+	}
+	for _, op := range circuit {
+		if _, isVector := op.(vectorOperation); isVector {
+			for _, w := range op.Inputs() {
+				o.pinned[w] = true
+			}
+		}
+	}
+	for _, op := range circuit {
+		o.add(op.Remap(o.resolve))
+	}
+	return removeDeadGates(o.circuit)
+}
+
+type optimizer struct {
+	circuit   Circuit
+	alias     map[WireID]WireID    // wires of removed gates, and the wires holding the same value
+	constants map[WireID]uint64    // wires whose value is known at compile time
+	producer  map[WireID]Operation // gate of the optimized circuit computing each wire
+	computed  map[string]Operation // operation of the optimized circuit computing each key
+	pinned    map[WireID]bool      // wires read by vector operations, which cannot be replaced by another wire
+}
+
+func (o *optimizer) resolve(w WireID) WireID {
+	if a, exists := o.alias[w]; exists {
+		return a
+	}
+	return w
+}
+
+// Simplify the operation and append it to the optimized circuit, unless it computes a wire already available
+func (o *optimizer) add(op Operation) {
+	op = o.simplify(op)
+	pinned := false
+	for _, w := range outputWires(op) {
+		pinned = pinned || o.pinned[w]
+	}
+
+	if in, isIdentity := identityInput(op); isIdentity && !pinned {
+		o.alias[op.Output()] = in
+		return
+	}
+
+	if key, isLocal := operationKey(op); isLocal && !pinned {
+		if computed, exists := o.computed[key]; exists {
+			existing := outputWires(computed)
+			for i, w := range outputWires(op) {
+				o.alias[w] = existing[i]
+			}
+			return
+		}
+		o.computed[key] = op
+	}
+
+	if value, isConstant := o.evalConstant(op); isConstant {
+		o.constants[op.Output()] = value
+	}
+	for _, w := range outputWires(op) {
+		o.producer[w] = op
+	}
+	o.circuit = append(o.circuit, op)
+}
+
+// Rewrite the operation into a cheaper one computing the same value, until no rule applies
+func (o *optimizer) simplify(op Operation) Operation {
+	for {
+		simplified := o.simplifyOnce(op)
+		if simplified == nil {
+			return op
+		}
+		op = simplified
+	}
+}
+
+// Returns the operation rewritten by the first rule that applies, or nil
+func (o *optimizer) simplifyOnce(op Operation) Operation {
+	switch op := op.(type) {
+	case *Add:
+		if c, isConstant := o.constants[op.In2]; isConstant {
+			if _, bothConstant := o.constants[op.In1]; !bothConstant {
+				return &AddCst{In: op.In1, CstValue: c, Out: op.Out}
+			}
+		}
+		if c, isConstant := o.constants[op.In1]; isConstant {
+			if _, bothConstant := o.constants[op.In2]; !bothConstant {
+				return &AddCst{In: op.In2, CstValue: c, Out: op.Out}
+			}
+		}
+	case *Sub:
+		if c, isConstant := o.constants[op.In2]; isConstant {
+			if _, bothConstant := o.constants[op.In1]; !bothConstant {
+				return &AddCst{In: op.In1, CstValue: (Params.T - c) % Params.T, Out: op.Out}
+			}
+		}
+	case *Mult:
+		// A product of two constant wires is also rewritten, so that it doesn't consume a triplet
+		if c, isConstant := o.constants[op.In2]; isConstant {
+			return &MultCst{In: op.In1, CstValue: c, Out: op.Out}
+		}
+		if c, isConstant := o.constants[op.In1]; isConstant {
+			return &MultCst{In: op.In2, CstValue: c, Out: op.Out}
+		}
+	case *AddCst:
+		if inner, isAddCst := o.producer[op.In].(*AddCst); isAddCst {
+			return &AddCst{In: inner.In, CstValue: (inner.CstValue%Params.T + op.CstValue%Params.T) % Params.T, Out: op.Out}
+		}
+	case *MultCst:
+		if inner, isMultCst := o.producer[op.In].(*MultCst); isMultCst {
+			return &MultCst{In: inner.In, CstValue: (inner.CstValue % Params.T) * (op.CstValue % Params.T) % Params.T, Out: op.Out}
+		}
+	}
+	return nil
+}
+
+// Returns the input wire of an operation computing the same value as its input, i.e. adding 0 or multiplying by 1
+func identityInput(op Operation) (WireID, bool) {
+	switch op := op.(type) {
+	case *AddCst:
+		return op.In, op.CstValue%Params.T == 0
+	case *MultCst:
+		return op.In, op.CstValue%Params.T == 1
+	}
+	return 0, false
+}
+
+// Returns the value of the output wire of the operation if it is known at compile time
+func (o *optimizer) evalConstant(op Operation) (uint64, bool) {
+	switch op := op.(type) {
+	case *Sub:
+		if op.In1 == op.In2 {
+			return 0, true
+		}
+	case *MultCst:
+		if op.CstValue%Params.T == 0 {
+			return 0, true
+		}
+	case inputOperation, *Reveal, *RevealTo, *BoolReveal, vectorOperation:
+		return 0, false
+	}
+
+	// Any other local operation is constant if all its inputs are
+	inputs := make(map[WireID]*big.Int)
+	for _, w := range op.Inputs() {
+		c, isConstant := o.constants[w]
+		if !isConstant {
+			return 0, false
+		}
+		inputs[w] = new(big.Int).SetUint64(c)
+	}
+	ce := &ClearEvaluation{Wires: inputs}
+	op.EvalClear(ce)
+	if ce.err != nil {
+		// Left in the circuit, to fail at evaluation time
+		return 0, false
+	}
+	return ce.Wires[op.Output()].Uint64(), true
+}
+
+// Returns a key identifying the computation of a local operation, independently of its output wire, so that two
+// operations with the same key compute the same value. Input and reveal gates have no key, since each of them is
+// needed
+func operationKey(op Operation) (string, bool) {
+	switch op.(type) {
+	case inputOperation, *Reveal, *RevealTo, *VecReveal, *BoolReveal:
+		return "", false
+	}
+
+	name, err := operationType(op)
+	if err != nil {
+		return "", false
+	}
+	inputs := op.Inputs()
+	switch op.(type) {
+	case *Add, *Mult, *Xor, *And:
+		inputs = append([]WireID(nil), inputs...)
+		sort.Slice(inputs, func(i, j int) bool { return inputs[i] < inputs[j] })
+	}
+	return fmt.Sprintf("%s%s %v", name, operationParameters(op), inputs), true
+}
+
+// Returns the circuit without the gates whose output doesn't reach any reveal gate. Input gates are always kept
+func removeDeadGates(circuit Circuit) Circuit {
+	live := make(map[WireID]bool)
+	keep := make([]bool, len(circuit))
+	for i := len(circuit) - 1; i >= 0; i-- {
+		op := circuit[i]
+		_, isInput := op.(inputOperation)
+		_, isPrivate := op.(privateOpeningOperation)
+		isLive := isInput || isPrivate || len(Circuit{op}.OutputWires()) > 0
+		for _, w := range outputWires(op) {
+			isLive = isLive || live[w]
+		}
+		if isLive {
+			keep[i] = true
+			for _, w := range op.Inputs() {
+				live[w] = true
+			}
+		}
+	}
+
+	var optimized Circuit
+	for i, op := range circuit {
+		if keep[i] {
+			optimized = append(optimized, op)
+		}
+	}
+	return optimized
+}
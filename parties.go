@@ -54,6 +54,7 @@ func (lp *LocalParty) String() string {
 type Message struct {
 	MPCMessage    *MPCMessage
 	BeaverMessage *BeaverMessage
+	BatchMessage  *BatchMessage
 }
 
 type MessageType uint64
@@ -61,6 +62,7 @@ type MessageType uint64
 const (
 	MPC MessageType = iota
 	Beaver
+	Batch
 )
 
 type RemoteParty struct {
@@ -122,6 +124,16 @@ func (lp *LocalParty) BindNetwork(nw *TCPNetworkStruct) {
 					check(err)
 
 					msg.BeaverMessage = &BeaverMessage{Size: size, Value: val}
+				case Batch:
+					var size uint64
+
+					err = binary.Read(conn, binary.BigEndian, &size)
+					check(err)
+					values := make([]uint64, size)
+					err = binary.Read(conn, binary.BigEndian, &values)
+					check(err)
+
+					msg.BatchMessage = &BatchMessage{Values: values}
 				default:
 					check(errors.New("unknown message type"))
 				}
@@ -144,6 +156,10 @@ func (lp *LocalParty) BindNetwork(nw *TCPNetworkStruct) {
 					check(binary.Write(conn, binary.BigEndian, MPC))
 					check(binary.Write(conn, binary.BigEndian, mpcMsg.Value))
 					check(binary.Write(conn, binary.BigEndian, mpcMsg.Out))
+				} else if batchMsg := m.BatchMessage; batchMsg != nil {
+					check(binary.Write(conn, binary.BigEndian, Batch))
+					check(binary.Write(conn, binary.BigEndian, uint64(len(batchMsg.Values))))
+					check(binary.Write(conn, binary.BigEndian, batchMsg.Values))
 				} else {
 					check(errors.New("no message to send"))
 				}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"math/big"
+	"math/bits"
+)
+
+// Power In^Exponent modulo q by square-and-multiply: with L the bit length of the
+// Exponent, the squares In^(2^k) are computed in the rounds 0 to L-2, and the running product of the squares of the
+// bits set in the Exponent is multiplied by In^(2^k) in round k, alongside the next squaring. The gate takes L-1
+// rounds if the Exponent is a power of 2 and L rounds otherwise, which is ceil(log2(Exponent)), the minimal depth of
+// the power, and L-1 + popcount(Exponent)-1 triplets keyed by Out and its internal wires. Since x^q = x for all x,
+// an Exponent e >= 1 is first reduced to ((e-1) mod (q-1)) + 1, which bounds L by 17. The reduced Exponents 0 and 1
+// are computed locally, as the constant 1 and a copy of In
+type PowCst struct {
+	In       WireID
+	Exponent uint64
+	Out      WireID
+}
+
+// Exponent actually computed by the gate
+func (po PowCst) exponent() uint64 {
+	return reduceExponent(po.Exponent)
+}
+
+// Returns the exponent e >= 1 reduced into [1, q-1], such that x^e is unchanged for all x, or 0 if e is 0
+func reduceExponent(e uint64) uint64 {
+	if e == 0 {
+		return 0
+	}
+	return (e-1)%(Params.T-1) + 1
+}
+
+// Bit length of the exponent
+func (po PowCst) length() int {
+	return bits.Len64(po.exponent())
+}
+
+// Returns the wire holding In^(2^k): In itself, then the internal wires Out+1 to Out+L-1
+func (po PowCst) square(k int) WireID {
+	if k == 0 {
+		return po.In
+	}
+	return po.Out + WireID(k)
+}
+
+// Internal wire holding the running product of the squares of the lower bits set in the exponent
+func (po PowCst) product() WireID {
+	return po.Out + WireID(po.length())
+}
+
+// Number of multiplications of round k: the squaring of In^(2^k), and the product by In^(2^k) if bit k is set and a
+// lower bit is set too
+func (po PowCst) roundMults(k int) (squaring bool, product bool) {
+	e := po.exponent()
+	lower := e & (1<<uint(k) - 1)
+	return k < po.length()-1, e&(1<<uint(k)) != 0 && lower != 0
+}
+
+// Returns the index of the first triplet used in the round
+func (po PowCst) firstTriplet(round int) int {
+	index := 0
+	for k := 0; k < round; k++ {
+		index += po.RoundOpenCount(k) / 2
+	}
+	return index
+}
+
+func (po PowCst) triplet(cep *Protocol, index int) BeaverTriplet {
+	return cep.BeaverTriplets[po.Out+WireID(index)]
+}
+
+// The internal wires hold the squares and the running product, and key the triplets after the one of Out
+func (po PowCst) InternalWires() int {
+	if po.exponent() < 2 {
+		return 0
+	}
+	if triplets := po.firstTriplet(po.OpenRounds()); triplets > po.length()+1 {
+		return triplets - 1
+	}
+	return po.length()
+}
+
+func (po PowCst) TripletWires() []WireID {
+	return vectorWires(po.Out, po.firstTriplet(po.OpenRounds()))
+}
+
+func (po PowCst) IsMult() bool {
+	return po.exponent() >= 2
+}
+
+func (po PowCst) Output() WireID {
+	return po.Out
+}
+
+func (po PowCst) Inputs() []WireID {
+	return []WireID{po.In}
+}
+
+func (po PowCst) Remap(f func(WireID) WireID) Operation {
+	return &PowCst{In: f(po.In), Exponent: po.Exponent, Out: f(po.Out)}
+}
+
+func (po PowCst) Eval(cep *Protocol) {
+	switch po.exponent() {
+	case 0:
+		cep.WireOutput[po.Out] = constantShare(cep, 1)
+	case 1:
+		cep.WireOutput[po.Out] = new(big.Int).Set(cep.WireOutput[po.In])
+	default:
+		evalRounds(cep, po)
+	}
+}
+
+func (po PowCst) EvalClear(ce *ClearEvaluation) {
+	ce.Wires[po.Out] = new(big.Int).Exp(ce.Wires[po.In], new(big.Int).SetUint64(po.exponent()), q)
+}
+
+// Round k squares In^(2^k) and multiplies the running product by it, the last round writing the running product to
+// Out. The last round of a power of 2 is its last squaring. The reduced Exponents 0 and 1 take no round
+func (po PowCst) OpenRounds() int {
+	if po.exponent() < 2 {
+		return 0
+	}
+	if e := po.exponent(); e&(e-1) == 0 {
+		return po.length() - 1
+	}
+	return po.length()
+}
+
+func (po PowCst) RoundOpenCount(round int) int {
+	squaring, product := po.roundMults(round)
+	count := 0
+	if squaring {
+		count += 2
+	}
+	if product {
+		count += 2
+	}
+	return count
+}
+
+func (po PowCst) RoundShares(cep *Protocol, round int) []*big.Int {
+	var shares []*big.Int
+	next := po.firstTriplet(round)
+	squaring, product := po.roundMults(round)
+	x := cep.WireOutput[po.square(round)]
+	if squaring {
+		shares = append(shares, beaverShares(x, x, po.triplet(cep, next))...)
+		next++
+	}
+	if product {
+		shares = append(shares, beaverShares(cep.WireOutput[po.product()], x, po.triplet(cep, next))...)
+	}
+	return shares
+}
+
+func (po PowCst) RoundOpen(cep *Protocol, round int, opened []*big.Int) {
+	next := po.firstTriplet(round)
+	squaring, product := po.roundMults(round)
+	x := cep.WireOutput[po.square(round)]
+	if squaring {
+		cep.WireOutput[po.square(round+1)] = beaverProduct(cep, x, x, po.triplet(cep, next), opened[:2])
+		opened = opened[2:]
+		next++
+	}
+	if product {
+		cep.WireOutput[po.product()] = beaverProduct(cep, cep.WireOutput[po.product()], x, po.triplet(cep, next), opened)
+	} else if po.exponent()&(1<<uint(round)) != 0 {
+		// Lowest bit set in the exponent
+		cep.WireOutput[po.product()] = new(big.Int).Set(x)
+	}
+
+	if round == po.OpenRounds()-1 {
+		if po.OpenRounds() < po.length() {
+			cep.WireOutput[po.Out] = new(big.Int).Set(cep.WireOutput[po.square(round+1)])
+		} else {
+			cep.WireOutput[po.Out] = new(big.Int).Set(cep.WireOutput[po.product()])
+		}
+	}
+}
+
+func (po PowCst) BeaverTriplet(count int) []BeaverTriplet {
+	return Mult{}.BeaverTriplet(count)
+}
+
+// Power x^exponent modulo q. The exponents reduced to 0 or 1 are computed without any PowCst gate
+func (x Wire) Pow(exponent uint64) Wire {
+	exponent = reduceExponent(exponent)
+	switch exponent {
+	case 0:
+		return x.MulConst(0).AddConst(1)
+	case 1:
+		return x
+	}
+	return x.b.emit(func(out WireID) Operation { return &PowCst{In: x.ID, Exponent: exponent, Out: out} })
+}
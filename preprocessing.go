@@ -0,0 +1,115 @@
+package main
+
+import (
+	"github.com/ldsec/lattigo/ring"
+	"math/big"
+)
+
+// Operations consuming several Beaver triplets, keyed by the given wires (usually internal wires of the operation)
+type tripletOperation interface {
+	Operation
+	TripletWires() []WireID
+}
+
+// Operations consuming shared random bits generated in the preprocessing phase. Like the Beaver triplets, the bits
+// are keyed by wire
+type randomBitsOperation interface {
+	Operation
+	RandomBitWires() []WireID // returns the wires keying the random bits consumed by the operation
+	FieldBits() bool          // whether the bits are those of uniform values below q, comparisonBits wires per value from the least significant bit, instead of independent bits
+}
+
+// Generate the random bits of all the operations of the circuit consuming some, for the parties 0 to parties-1
+func DealRandomBits(circuit Circuit, parties int) map[PartyID]map[WireID]*big.Int {
+	randomBits := make(map[PartyID]map[WireID]*big.Int, parties)
+	for id := 0; id < parties; id++ {
+		randomBits[PartyID(id)] = make(map[WireID]*big.Int)
+	}
+	for _, op := range circuit {
+		ro, isRandom := op.(randomBitsOperation)
+		if !isRandom {
+			continue
+		}
+		wires := ro.RandomBitWires()
+		for len(wires) > 0 {
+			value, bits := ring.RandInt(big.NewInt(2)), 1
+			if ro.FieldBits() {
+				value, bits = ring.RandInt(q), comparisonBits
+			}
+			for i, w := range wires[:bits] {
+				for id, share := range shareValue(big.NewInt(int64(value.Bit(i))), parties) {
+					randomBits[PartyID(id)][w] = share
+				}
+			}
+			wires = wires[bits:]
+		}
+	}
+	return randomBits
+}
+
+// Returns our share of z + r mod q, where r is the random value whose bits are keyed by the given wires, from the
+// least significant one
+func maskShare(cep *Protocol, z *big.Int, bitWires []WireID) *big.Int {
+	masked := new(big.Int).Set(z)
+	for i, w := range bitWires {
+		masked.Add(masked, new(big.Int).Lsh(cep.RandomBits[w], uint(i)))
+	}
+	return masked.Mod(masked, q)
+}
+
+// Split the value into 'count' additive shares modulo q
+func shareValue(value *big.Int, count int) []*big.Int {
+	shares := make([]*big.Int, count)
+	sum := big.NewInt(0)
+	for i := 0; i < count-1; i++ {
+		shares[i] = ring.RandInt(q)
+		sum.Add(sum, shares[i])
+	}
+	shares[count-1] = new(big.Int).Sub(value, sum)
+	shares[count-1].Mod(shares[count-1], q)
+	return shares
+}
+
+// Returns our share of the public constant: party 0 holds the constant, the others 0
+func constantShare(cep *Protocol, value int64) *big.Int {
+	if cep.ID == 0 {
+		return big.NewInt(value)
+	}
+	return big.NewInt(0)
+}
+
+// Returns our shares of x-a and y-b, to be opened to multiply the shared values x and y with the triplet (a, b, c)
+func beaverShares(x, y *big.Int, triplet BeaverTriplet) []*big.Int {
+	X_a := new(big.Int).Sub(x, triplet.a)
+	X_a.Mod(X_a, q)
+	Y_b := new(big.Int).Sub(y, triplet.b)
+	Y_b.Mod(Y_b, q)
+	return []*big.Int{X_a, Y_b}
+}
+
+// Returns our share of x*y, given the opened values x-a and y-b
+func beaverProduct(cep *Protocol, x, y *big.Int, triplet BeaverTriplet, opened []*big.Int) *big.Int {
+	z := new(big.Int).Set(triplet.c)
+	z.Add(z, new(big.Int).Mul(x, opened[1]))
+	z.Add(z, new(big.Int).Mul(y, opened[0]))
+	if cep.ID == 0 {
+		z.Sub(z, new(big.Int).Mul(opened[0], opened[1]))
+	}
+	return z.Mod(z, q)
+}
+
+// Evaluate a multi-round operation on its own, one round after the other
+func evalRounds(cep *Protocol, mr multiRoundOperation) {
+	for round := 0; round < mr.OpenRounds(); round++ {
+		mr.RoundOpen(cep, round, cep.openPublic(mr.RoundShares(cep, round)))
+	}
+}
+
+// Open the given shared values to all the parties in a single round
+func (cep *Protocol) openPublic(shares []*big.Int) []*big.Int {
+	recipients := make([]PartyID, len(shares))
+	for i := range recipients {
+		recipients[i] = AllParties
+	}
+	return cep.openShares(shares, recipients, nil)
+}
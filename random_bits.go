@@ -0,0 +1,185 @@
+package main
+
+import (
+	"github.com/ldsec/lattigo/ring"
+	"math/big"
+)
+
+// Generation of shared random bits by the parties themselves, instead of a trusted dealer. A bit is obtained from a
+// shared uniform value a: a^2 is computed with a Beaver triplet and opened, and (a/s + 1)/2 is a uniform bit, where s
+// is the square root of a^2 lower than q/2, since a/s is 1 or -1 with the same probability. The bits of a uniform
+// value below q are obtained by rejection: the bits of candidates below 2^m are generated, where m is the bit length
+// of q, and [r < q] is opened for each candidate r to discard those that are not below q
+type RandomBitProtocol struct {
+	*Protocol
+	nextTriplet func() BeaverTriplet // returns our share of the next unused Beaver triplet
+}
+
+// Create a new random bit generation protocol, consuming the Beaver triplets returned by 'nextTriplet'. The parties
+// must consume the same triplets in the same order
+func (lp *LocalParty) NewRandomBitProtocol(nextTriplet func() BeaverTriplet) *RandomBitProtocol {
+	return &RandomBitProtocol{Protocol: lp.NewProtocol(nil, nil, nil), nextTriplet: nextTriplet}
+}
+
+// Returns our shares of the random bits of all the operations of the circuit, keyed by wire like DealRandomBits
+func (cep *RandomBitProtocol) Generate(circuit Circuit) map[WireID]*big.Int {
+	var fieldWires, bitWires []WireID
+	for _, op := range circuit {
+		if ro, isRandom := op.(randomBitsOperation); isRandom && ro.FieldBits() {
+			fieldWires = append(fieldWires, ro.RandomBitWires()...)
+		} else if isRandom {
+			bitWires = append(bitWires, ro.RandomBitWires()...)
+		}
+	}
+
+	randomBits := make(map[WireID]*big.Int, len(fieldWires)+len(bitWires))
+	for i, bits := range cep.FieldBits(len(fieldWires) / comparisonBits) {
+		for j, bit := range bits {
+			randomBits[fieldWires[i*comparisonBits+j]] = bit
+		}
+	}
+	for i, bit := range cep.Bits(len(bitWires)) {
+		randomBits[bitWires[i]] = bit
+	}
+	return randomBits
+}
+
+// Returns our shares of 'count' uniformly random bits. The squares are computed in one round and opened in a second
+// one, and the rare values a = 0 are generated again
+func (cep *RandomBitProtocol) Bits(count int) []*big.Int {
+	half := new(big.Int).Rsh(q, 1)
+	inverse2 := new(big.Int).ModInverse(big.NewInt(2), q)
+
+	var bits []*big.Int
+	for len(bits) < count {
+		a := make([]*big.Int, count-len(bits))
+		triplets := make([]BeaverTriplet, len(a))
+		var shares []*big.Int
+		for i := range a {
+			a[i] = ring.RandInt(q)
+			triplets[i] = cep.nextTriplet()
+			shares = append(shares, beaverShares(a[i], a[i], triplets[i])...)
+		}
+		opened := cep.openPublic(shares)
+
+		squares := make([]*big.Int, len(a))
+		for i := range a {
+			squares[i] = beaverProduct(cep.Protocol, a[i], a[i], triplets[i], opened[2*i:2*i+2])
+		}
+
+		for i, square := range cep.openPublic(squares) {
+			if square.Sign() == 0 {
+				continue
+			}
+			s := new(big.Int).ModSqrt(square, q)
+			if s.Cmp(half) > 0 {
+				s.Sub(q, s)
+			}
+			bit := new(big.Int).Mul(a[i], s.ModInverse(s, q))
+			bit.Add(bit, constantShare(cep.Protocol, 1))
+			bit.Mul(bit, inverse2)
+			bits = append(bits, bit.Mod(bit, q))
+		}
+	}
+	return bits
+}
+
+// Returns our shares of the bits of 'count' uniformly random values below q, from the least significant bit. For
+// q = 2^16+1, about half of the candidates are discarded, so twice as many candidates as missing values are generated
+// at each attempt
+func (cep *RandomBitProtocol) FieldBits(count int) [][]*big.Int {
+	var values [][]*big.Int
+	for len(values) < count {
+		bits := cep.Bits(fieldCandidates(count-len(values)) * comparisonBits)
+		candidates := make([][]*big.Int, len(bits)/comparisonBits)
+		for i := range candidates {
+			candidates[i] = bits[i*comparisonBits : (i+1)*comparisonBits]
+		}
+		for i, below := range cep.belowQ(candidates) {
+			if below.Sign() != 0 && len(values) < count {
+				values = append(values, candidates[i])
+			}
+		}
+	}
+	return values
+}
+
+// Number of candidates generated by FieldBits at once for the given number of missing values
+func fieldCandidates(missing int) int {
+	return 2*missing + 1
+}
+
+// Open [r < q] for each candidate r given by its shared bits. Like in a LessThan gate, [r < q] restricted to the bits
+// 0 to i is a_i + b_i*[r < q] restricted to the bits 0 to i-1, with a_i and b_i affine in r_i since q is public, and
+// the a_i and b_i are reduced in a tree of log2(m) rounds, batched over the candidates
+func (cep *RandomBitProtocol) belowQ(candidates [][]*big.Int) []*big.Int {
+	a := make([][]*big.Int, len(candidates))
+	b := make([][]*big.Int, len(candidates))
+	for k, bits := range candidates {
+		a[k] = make([]*big.Int, comparisonBits)
+		b[k] = make([]*big.Int, comparisonBits)
+		for i, r := range bits {
+			notR := new(big.Int).Sub(constantShare(cep.Protocol, 1), r)
+			if q.Bit(i) == 1 {
+				a[k][i], b[k][i] = notR, new(big.Int).Set(r)
+			} else {
+				a[k][i], b[k][i] = big.NewInt(0), notR
+			}
+		}
+	}
+
+	for _, pairs := range lessThanLevels {
+		var shares []*big.Int
+		var triplets []BeaverTriplet
+		for k := range candidates {
+			for _, pair := range pairs {
+				low, high := pair[0], pair[1]
+				triplets = append(triplets, cep.nextTriplet())
+				shares = append(shares, beaverShares(b[k][high], a[k][low], triplets[len(triplets)-1])...)
+				if pairMults(pair) == 2 {
+					triplets = append(triplets, cep.nextTriplet())
+					shares = append(shares, beaverShares(b[k][high], b[k][low], triplets[len(triplets)-1])...)
+				}
+			}
+		}
+		opened := cep.openPublic(shares)
+
+		for k := range candidates {
+			for _, pair := range pairs {
+				low, high := pair[0], pair[1]
+				product := beaverProduct(cep.Protocol, b[k][high], a[k][low], triplets[0], opened[:2])
+				product.Add(product, a[k][high])
+				a[k][low] = product.Mod(product, q)
+				if pairMults(pair) == 2 {
+					b[k][low] = beaverProduct(cep.Protocol, b[k][high], b[k][low], triplets[1], opened[2:4])
+				}
+				triplets = triplets[pairMults(pair):]
+				opened = opened[2*pairMults(pair):]
+			}
+		}
+	}
+
+	below := make([]*big.Int, len(candidates))
+	for k := range candidates {
+		below[k] = a[k][0]
+	}
+	return cep.openPublic(below)
+}
+
+// Expected number of Beaver triplets consumed by RandomBitProtocol.Generate for the circuit, when no value is
+// generated again
+func randomBitTriplets(circuit Circuit) int {
+	var values, bits int
+	for _, op := range circuit {
+		if ro, isRandom := op.(randomBitsOperation); isRandom && ro.FieldBits() {
+			values += len(ro.RandomBitWires()) / comparisonBits
+		} else if isRandom {
+			bits += len(ro.RandomBitWires())
+		}
+	}
+	if values == 0 {
+		return bits
+	}
+	comparisonTriplets := LessThan{}.firstTriplet(len(lessThanLevels) + 1)
+	return bits + fieldCandidates(values)*(comparisonBits+comparisonTriplets)
+}
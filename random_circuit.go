@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// Generate a random well-formed circuit with 'gates' arithmetic gates (Add, Sub, AddCst, MultCst and Mult) over
+// 'parties' parties listening on localhost, from port 6660. Each party provides one or two random inputs, each gate
+// reads random wires computed before it, and the last wire is revealed along with a few others. The expected outputs
+// are computed in clear
+func RandomCircuit(rng *rand.Rand, parties int, gates int) *TestCircuit {
+	testCircuit := &TestCircuit{
+		Peers:  make(map[PartyID]string, parties),
+		Inputs: make(map[PartyID]map[GateID]uint64, parties),
+	}
+
+	b := NewCircuitBuilder()
+	var wires []Wire
+	for i := 0; i < parties; i++ {
+		id := PartyID(i)
+		testCircuit.Peers[id] = fmt.Sprintf("localhost:%d", 6660+i)
+		testCircuit.Inputs[id] = make(map[GateID]uint64)
+		for n := 1 + rng.Intn(2); n > 0; n-- {
+			w := b.Input(id)
+			testCircuit.Inputs[id][GateID(w.ID)] = uint64(rng.Int63n(int64(Params.T)))
+			wires = append(wires, w)
+		}
+	}
+
+	pick := func() Wire {
+		return wires[rng.Intn(len(wires))]
+	}
+	for i := 0; i < gates; i++ {
+		var w Wire
+		switch rng.Intn(5) {
+		case 0:
+			w = pick().Add(pick())
+		case 1:
+			w = pick().Sub(pick())
+		case 2:
+			w = pick().AddConst(uint64(rng.Int63n(int64(Params.T))))
+		case 3:
+			w = pick().MulConst(uint64(rng.Int63n(int64(Params.T))))
+		case 4:
+			w = pick().Mul(pick())
+		}
+		wires = append(wires, w)
+	}
+
+	for n := rng.Intn(3); n > 0; n-- {
+		b.Reveal(pick())
+	}
+	b.Reveal(wires[len(wires)-1])
+
+	testCircuit.Circuit = b.Circuit()
+	ce, err := EvaluateCircuit(testCircuit.Circuit, testCircuit.Inputs)
+	check(err)
+	testCircuit.ExpOutputs = ce.Outputs
+	return testCircuit
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// Error raised by a checked Select gate whose condition is not a bit, which stops the evaluation of the circuit
+type NotABitError struct {
+	Op Operation // faulty Select gate
+}
+
+func (e *NotABitError) Error() string {
+	return fmt.Sprintf("operation %T of wire %d has a condition which is not a bit", e.Op, e.Op.Output())
+}
+
+// Oblivious selection of IfTrue if the shared bit Cond is 1 and IfFalse if it is 0, computed as IfFalse +
+// Cond*(IfTrue-IfFalse) with the Beaver triplet keyed by Out, in one round. With Check, meant for testing, the gate also
+// computes Cond*(Cond-1) with a second triplet keyed by Out+1, and opens it in a second round: it is 0 if Cond is a
+// bit, and the evaluation fails with a NotABitError otherwise. Opening it leaks nothing about a valid condition
+type Select struct {
+	Cond    WireID
+	IfTrue  WireID
+	IfFalse WireID
+	Out     WireID
+	Check   bool
+}
+
+// With Check, the internal wire keys the second triplet and holds Cond*(Cond-1) between both rounds
+func (so Select) InternalWires() int {
+	if so.Check {
+		return 1
+	}
+	return 0
+}
+
+func (so Select) IsMult() bool {
+	return true
+}
+
+func (so Select) Output() WireID {
+	return so.Out
+}
+
+func (so Select) Inputs() []WireID {
+	return []WireID{so.Cond, so.IfTrue, so.IfFalse}
+}
+
+func (so Select) Remap(f func(WireID) WireID) Operation {
+	return &Select{Cond: f(so.Cond), IfTrue: f(so.IfTrue), IfFalse: f(so.IfFalse), Out: f(so.Out), Check: so.Check}
+}
+
+func (so Select) Eval(cep *Protocol) {
+	evalRounds(cep, so)
+}
+
+func (so Select) EvalClear(ce *ClearEvaluation) {
+	cond := new(big.Int).Mod(ce.Wires[so.Cond], q)
+	if so.Check && cond.Cmp(big.NewInt(1)) > 0 {
+		ce.fail(&NotABitError{Op: so})
+	}
+	z := new(big.Int).Sub(ce.Wires[so.IfTrue], ce.Wires[so.IfFalse])
+	z.Mul(z, cond)
+	z.Add(z, ce.Wires[so.IfFalse])
+	ce.Wires[so.Out] = z.Mod(z, q)
+}
+
+// Returns our share of IfTrue-IfFalse
+func (so Select) difference(cep *Protocol) *big.Int {
+	return new(big.Int).Sub(cep.WireOutput[so.IfTrue], cep.WireOutput[so.IfFalse])
+}
+
+// Returns our share of Cond-1
+func (so Select) condMinusOne(cep *Protocol) *big.Int {
+	return new(big.Int).Sub(cep.WireOutput[so.Cond], constantShare(cep, 1))
+}
+
+// Round 0 computes the selection, and with Check the product Cond*(Cond-1), opened in round 1
+func (so Select) OpenRounds() int {
+	if so.Check {
+		return 2
+	}
+	return 1
+}
+
+func (so Select) RoundOpenCount(round int) int {
+	switch {
+	case round == 1:
+		return 1
+	case so.Check:
+		return 4
+	default:
+		return 2
+	}
+}
+
+func (so Select) RoundShares(cep *Protocol, round int) []*big.Int {
+	if round == 1 {
+		return []*big.Int{cep.WireOutput[so.Out+1]}
+	}
+	shares := beaverShares(cep.WireOutput[so.Cond], so.difference(cep), cep.BeaverTriplets[so.Out])
+	if so.Check {
+		shares = append(shares, beaverShares(cep.WireOutput[so.Cond], so.condMinusOne(cep), cep.BeaverTriplets[so.Out+1])...)
+	}
+	return shares
+}
+
+func (so Select) RoundOpen(cep *Protocol, round int, opened []*big.Int) {
+	if round == 1 {
+		if opened[0].Sign() != 0 {
+			cep.fail(&NotABitError{Op: so})
+		}
+		return
+	}
+	z := beaverProduct(cep, cep.WireOutput[so.Cond], so.difference(cep), cep.BeaverTriplets[so.Out], opened[:2])
+	z.Add(z, cep.WireOutput[so.IfFalse])
+	cep.WireOutput[so.Out] = z.Mod(z, q)
+	if so.Check {
+		cep.WireOutput[so.Out+1] = beaverProduct(cep, cep.WireOutput[so.Cond], so.condMinusOne(cep), cep.BeaverTriplets[so.Out+1], opened[2:])
+	}
+}
+
+func (so Select) BeaverTriplet(count int) []BeaverTriplet {
+	return Mult{}.BeaverTriplet(count)
+}
+
+// Selection of ifTrue if the shared bit c is 1 and ifFalse if it is 0
+func (c Wire) Select(ifTrue, ifFalse Wire) Wire {
+	return c.selectWires(ifTrue, ifFalse, false)
+}
+
+// Selection of ifTrue if the shared bit c is 1 and ifFalse if it is 0, the evaluation failing with a NotABitError
+// if c is not a bit. It takes one more round, and is meant for testing
+func (c Wire) SelectChecked(ifTrue, ifFalse Wire) Wire {
+	return c.selectWires(ifTrue, ifFalse, true)
+}
+
+func (c Wire) selectWires(ifTrue, ifFalse Wire, check bool) Wire {
+	c.b.check(ifTrue)
+	c.b.check(ifFalse)
+	return c.b.emit(func(out WireID) Operation {
+		return &Select{Cond: c.ID, IfTrue: ifTrue.ID, IfFalse: ifFalse.ID, Out: out, Check: check}
+	})
+}
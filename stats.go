@@ -0,0 +1,139 @@
+package main
+
+import (
+	"github.com/ldsec/lattigo/bfv"
+)
+
+// Size in bytes of the header of a BatchMessage on the network (message type and number of values)
+const batchHeaderSize = 16
+
+// Size in bytes of an opened value on the network
+const valueSize = 8
+
+// Cost of the evaluation of a circuit, estimated without running it
+type CircuitStats struct {
+	Parties              int            `json:"parties"`                // number of parties the estimation is made for
+	Gates                map[string]int `json:"gates"`                  // number of gates of each type
+	MultDepth            int            `json:"mult_depth"`             // multiplicative depth of the circuit
+	BeaverTriplets       int            `json:"beaver_triplets"`        // number of Beaver triplets consumed
+	RandomBits           int            `json:"random_bits"`            // number of preprocessed random bits consumed
+	MatrixTriplets       int            `json:"matrix_triplets"`        // number of matrix triplets consumed
+	BinaryTriplets       int            `json:"binary_triplets"`        // number of binary triplets consumed, of 64 bits each
+	HEBatches            int            `json:"he_batches"`             // runs of BeaverProtocol done by ComputeBeaverTripletHE, ComputeRandomBitsHE and ComputeMatrixTripletsHE
+	Rounds               int            `json:"rounds"`                 // communication rounds of Protocol.Run
+	SequentialRounds     int            `json:"sequential_rounds"`      // communication rounds of Protocol.RunSequential
+	OnlineBytes          uint64         `json:"online_bytes"`           // bytes sent by all the parties during Protocol.Run
+	HEPreprocessingBytes uint64         `json:"he_preprocessing_bytes"` // bytes sent by all the parties to generate the triplets with BFV
+}
+
+// Estimate the cost of evaluating the circuit between 'parties' parties, with IDs 0 to parties-1
+func ComputeStats(circuit Circuit, parties int) (*CircuitStats, error) {
+	stats := &CircuitStats{
+		Parties: parties,
+		Gates:   make(map[string]int),
+	}
+
+	depths := MultiplicativeDepths(circuit)
+	for i, op := range circuit {
+		name, err := operationType(op)
+		if err != nil {
+			return nil, err
+		}
+		stats.Gates[name]++
+
+		stats.BeaverTriplets += len(tripletWires(op))
+		if _, isBinary := op.(binaryTripletOperation); isBinary {
+			stats.BinaryTriplets++
+		}
+		if ro, isRandom := op.(randomBitsOperation); isRandom {
+			stats.RandomBits += len(ro.RandomBitWires())
+		}
+		if mr, isMultiRound := op.(multiRoundOperation); isMultiRound {
+			stats.SequentialRounds += mr.OpenRounds()
+		} else if _, isInput := op.(inputOperation); isInput || op.IsMult() {
+			stats.SequentialRounds++
+		} else if _, isOpening := op.(openingOperation); isOpening {
+			stats.SequentialRounds++
+		}
+
+		if depths[i] > stats.MultDepth {
+			stats.MultDepth = depths[i]
+		}
+	}
+
+	slots := 1 << Params.LogN
+	stats.HEBatches = (stats.BeaverTriplets + slots - 1) / slots
+	stats.HEBatches += (randomBitTriplets(circuit) + slots - 1) / slots
+	matrixOps, matrixSlots := matrixTripletSlots(circuit)
+	stats.MatrixTriplets = len(matrixOps)
+	stats.HEBatches += (matrixSlots + slots - 1) / slots
+
+	for _, layer := range Layers(circuit) {
+		if len(layer.Inputs) > 0 {
+			stats.Rounds++
+			stats.OnlineBytes += inputBytes(layer.Inputs, parties)
+		}
+		if len(layer.Openings) > 0 || len(layer.Rounds) > 0 {
+			stats.Rounds++
+			stats.OnlineBytes += openingBytes(layer, parties)
+		}
+	}
+
+	ciphertext, err := bfv.NewCiphertext(Params, 1).MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	// In each run, every party sends two ciphertexts (d_i and d_ij) to each peer
+	stats.HEPreprocessingBytes = uint64(stats.HEBatches*2*parties*(parties-1)) * uint64(batchHeaderSize+len(ciphertext))
+
+	return stats, nil
+}
+
+// Bytes sent to share the inputs: each party sends one message to each peer with its shares of all its inputs
+func inputBytes(inputs []Operation, parties int) uint64 {
+	owned := make(map[PartyID]uint64)
+	for _, op := range inputs {
+		owned[op.(inputOperation).Owner()] += uint64(len(outputWires(op)))
+	}
+
+	var bytes uint64
+	for _, count := range owned {
+		bytes += uint64(parties-1) * (batchHeaderSize + count*valueSize)
+	}
+	return bytes
+}
+
+// Bytes sent to open the values of a layer: each party sends one message to each peer with its shares of the values
+// opened to all the parties and of the values opened to this peer only
+func openingBytes(layer Layer, parties int) uint64 {
+	var public uint64
+	private := make(map[PartyID]uint64)
+	for _, op := range layer.Openings {
+		if mr, isMultiRound := op.(multiRoundOperation); isMultiRound {
+			public += uint64(mr.RoundOpenCount(0))
+			continue
+		}
+		oo, isOpening := op.(openingOperation)
+		if !isOpening {
+			continue
+		}
+		if po, isPrivate := op.(privateOpeningOperation); isPrivate {
+			private[po.Recipient()] += uint64(oo.OpenCount())
+		} else {
+			public += uint64(oo.OpenCount())
+		}
+	}
+	for _, r := range layer.Rounds {
+		public += uint64(r.Op.RoundOpenCount(r.Index))
+	}
+
+	var bytes uint64
+	for sender := 0; sender < parties; sender++ {
+		for receiver := 0; receiver < parties; receiver++ {
+			if values := public + private[PartyID(receiver)]; sender != receiver && values > 0 {
+				bytes += batchHeaderSize + values*valueSize
+			}
+		}
+	}
+	return bytes
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+)
+
+// Circuit computing its results from parameter wires, which can be instantiated many times in larger circuits. The
+// parameters are not computed by any operation of the sub-circuit: each instance reads the wires given as arguments
+// instead
+type SubCircuit struct {
+	Params  []WireID
+	Results []WireID
+	Circuit Circuit
+}
+
+// Define a sub-circuit with 'params' parameters: 'body' builds its operations from the parameter wires, and returns
+// its result wires
+func NewSubCircuit(params int, body func(b *CircuitBuilder, params []Wire) []Wire) *SubCircuit {
+	b := NewCircuitBuilder()
+	sc := &SubCircuit{Params: make([]WireID, params)}
+	wires := make([]Wire, params)
+	for i := range wires {
+		wires[i] = Wire{b, b.alloc()}
+		sc.Params[i] = wires[i].ID
+	}
+
+	for _, w := range body(b, wires) {
+		b.check(w)
+		sc.Results = append(sc.Results, w.ID)
+	}
+	sc.Circuit = b.Circuit()
+	return sc
+}
+
+// Returns the operations of an instance of the sub-circuit reading the wires 'args', its result wires, and the
+// mapping from the wires of the sub-circuit to the wires of the instance. The wires computed by the instance are
+// allocated from 'next', in the order of the operations
+func (sc *SubCircuit) Instantiate(args []WireID, next WireID) (Circuit, []WireID, map[WireID]WireID, error) {
+	if len(args) != len(sc.Params) {
+		return nil, nil, nil, fmt.Errorf("sub-circuit takes %d arguments, %d given", len(sc.Params), len(args))
+	}
+
+	wires := make(map[WireID]WireID, len(sc.Params)+len(sc.Circuit))
+	for i, param := range sc.Params {
+		wires[param] = args[i]
+	}
+	for _, op := range sc.Circuit {
+		for _, w := range outputWires(op) {
+			wires[w] = next
+			next++
+		}
+	}
+
+	remap := func(w WireID) WireID { return wires[w] }
+	instance := make(Circuit, len(sc.Circuit))
+	for i, op := range sc.Circuit {
+		instance[i] = op.Remap(remap)
+	}
+	results := make([]WireID, len(sc.Results))
+	for i, w := range sc.Results {
+		results[i] = wires[w]
+	}
+	return instance, results, wires, nil
+}
+
+// Append an instance of the sub-circuit reading the wires 'args' to the circuit, and return its result wires
+func (b *CircuitBuilder) Call(sc *SubCircuit, args ...Wire) []Wire {
+	ids := make([]WireID, len(args))
+	for i, w := range args {
+		b.check(w)
+		ids[i] = w.ID
+	}
+
+	instance, results, _, err := sc.Instantiate(ids, b.next)
+	check(err)
+	for _, op := range instance {
+		b.next += WireID(len(outputWires(op)))
+	}
+	b.circuit = append(b.circuit, instance...)
+
+	wires := make([]Wire, len(results))
+	for i, w := range results {
+		wires[i] = Wire{b, w}
+	}
+	return wires
+}
+
+// Returns the triplets of the multiplications of a sub-circuit, keyed by the output wires of an instance instead,
+// given the mapping returned by SubCircuit.Instantiate
+func RemapBeaverTriplets(triplets map[WireID]BeaverTriplet, wires map[WireID]WireID) map[WireID]BeaverTriplet {
+	remapped := make(map[WireID]BeaverTriplet, len(triplets))
+	for w, triplet := range triplets {
+		remapped[wires[w]] = triplet
+	}
+	return remapped
+}
+
+// Sub-circuit computing the sum of its n parameters
+func SumSubCircuit(n int) *SubCircuit {
+	return NewSubCircuit(n, func(b *CircuitBuilder, params []Wire) []Wire {
+		sum := params[0]
+		for _, w := range params[1:] {
+			sum = sum.Add(w)
+		}
+		return []Wire{sum}
+	})
+}
+
+// Sub-circuit evaluating the polynomial with the coefficients 'coeffs' (constant term first) at its parameter, using
+// Horner's method
+func PolynomialSubCircuit(coeffs []uint64) *SubCircuit {
+	return NewSubCircuit(1, func(b *CircuitBuilder, params []Wire) []Wire {
+		x := params[0]
+		if len(coeffs) == 1 {
+			return []Wire{x.Sub(x).AddConst(coeffs[0])}
+		}
+		acc := x.MulConst(coeffs[len(coeffs)-1])
+		for i := len(coeffs) - 2; i >= 0; i-- {
+			acc = acc.AddConst(coeffs[i])
+			if i > 0 {
+				acc = acc.Mul(x)
+			}
+		}
+		return []Wire{acc}
+	})
+}
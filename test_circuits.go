@@ -1,13 +1,14 @@
 package main
 
 type TestCircuit struct {
-	Peers     map[PartyID]string            // Mapping from PartyID to network addresses
-	Inputs    map[PartyID]map[GateID]uint64 // The partys' input for each gate
-	Circuit   []Operation                   // Circuit definition
-	ExpOutput uint64                        // Expected output
+	Peers             map[PartyID]string            `json:"peers"`                              // Mapping from PartyID to network addresses
+	Inputs            map[PartyID]map[GateID]uint64 `json:"inputs"`                             // The partys' input for each of their Input gates, indexed by its output wire
+	Circuit           Circuit                       `json:"circuit"`                            // Circuit definition
+	ExpOutputs        map[WireID]uint64             `json:"expected_outputs"`                   // Expected output of each Reveal gate
+	ExpPrivateOutputs map[WireID]uint64             `json:"expected_private_outputs,omitempty"` // Expected output of each RevealTo gate, only learned by its recipient
 }
 
-var TestCircuits = []*TestCircuit{&Circuit1, &Circuit2, &Circuit3, &Circuit4, &Circuit5, &Circuit6, &Circuit7, &Circuit8, &Circuit9, &Circuit10}
+var TestCircuits = []*TestCircuit{&Circuit1, &Circuit2, &Circuit3, &Circuit4, &Circuit5, &Circuit6, &Circuit7, &Circuit8, &Circuit9, &Circuit10, &Circuit11, &Circuit12, &Circuit13, &Circuit14, &Circuit15, &Circuit16, &Circuit17, &Circuit18, &Circuit19, &Circuit20, &Circuit21, &Circuit22, &Circuit23, &Circuit24}
 
 var Circuit1 = TestCircuit{
 	// f(a,b,c) = a + b + c
@@ -49,7 +50,7 @@ var Circuit1 = TestCircuit{
 			Out: 5,
 		},
 	},
-	ExpOutput: 67,
+	ExpOutputs: map[WireID]uint64{5: 67},
 }
 
 var Circuit2 = TestCircuit{ // TODO check the ordering of the wires
@@ -81,7 +82,7 @@ var Circuit2 = TestCircuit{ // TODO check the ordering of the wires
 			Out: 3,
 		},
 	},
-	ExpOutput: 10,
+	ExpOutputs: map[WireID]uint64{3: 10},
 }
 
 var Circuit3 = TestCircuit{
@@ -129,7 +130,7 @@ var Circuit3 = TestCircuit{
 			Out: 6,
 		},
 	},
-	ExpOutput: 115,
+	ExpOutputs: map[WireID]uint64{6: 115},
 }
 
 var Circuit4 = TestCircuit{
@@ -177,7 +178,7 @@ var Circuit4 = TestCircuit{
 			Out: 6,
 		},
 	},
-	ExpOutput: 30,
+	ExpOutputs: map[WireID]uint64{6: 30},
 }
 
 var Circuit5 = TestCircuit{
@@ -230,7 +231,7 @@ var Circuit5 = TestCircuit{
 			Out: 7,
 		},
 	},
-	ExpOutput: 35,
+	ExpOutputs: map[WireID]uint64{7: 35},
 }
 
 var Circuit6 = TestCircuit{
@@ -284,7 +285,7 @@ var Circuit6 = TestCircuit{
 			Out: 7,
 		},
 	},
-	ExpOutput: 140,
+	ExpOutputs: map[WireID]uint64{7: 140},
 }
 
 var Circuit7 = TestCircuit{
@@ -342,7 +343,7 @@ var Circuit7 = TestCircuit{
 			Out: 8,
 		},
 	},
-	ExpOutput: 161,
+	ExpOutputs: map[WireID]uint64{8: 161},
 }
 
 var Circuit8 = TestCircuit{
@@ -417,7 +418,7 @@ var Circuit8 = TestCircuit{
 			Out: 11,
 		},
 	},
-	ExpOutput: 666,
+	ExpOutputs: map[WireID]uint64{11: 666},
 }
 var Circuit9 = TestCircuit{
 	// f(a,b) = a * b
@@ -448,7 +449,7 @@ var Circuit9 = TestCircuit{
 			Out: 3,
 		},
 	},
-	ExpOutput: 88,
+	ExpOutputs: map[WireID]uint64{3: 88},
 }
 
 var Circuit10 = TestCircuit{
@@ -526,5 +527,889 @@ var Circuit10 = TestCircuit{
 			Out: 12,
 		},
 	},
-	ExpOutput: 538,
+	ExpOutputs: map[WireID]uint64{12: 538},
+}
+
+var Circuit11 = TestCircuit{
+	// f(a,b,c) = (a + b + c, a*b, a*b*c)
+	Peers: map[PartyID]string{
+		0: "localhost:6660",
+		1: "localhost:6661",
+		2: "localhost:6662",
+	},
+	Inputs: map[PartyID]map[GateID]uint64{
+		0: {0: 4},
+		1: {1: 6},
+		2: {2: 5},
+	},
+	Circuit: []Operation{
+		&Input{
+			Party: 0,
+			Out:   0,
+		},
+		&Input{
+			Party: 1,
+			Out:   1,
+		},
+		&Input{
+			Party: 2,
+			Out:   2,
+		},
+		&Add{
+			In1: 0,
+			In2: 1,
+			Out: 3,
+		},
+		&Add{
+			In1: 3,
+			In2: 2,
+			Out: 4,
+		},
+		&Reveal{
+			In:  4,
+			Out: 5,
+		},
+		&Mult{
+			In1: 0,
+			In2: 1,
+			Out: 6,
+		},
+		&Reveal{
+			In:  6,
+			Out: 7,
+		},
+		&Mult{
+			In1: 6,
+			In2: 2,
+			Out: 8,
+		},
+		&Reveal{
+			In:  8,
+			Out: 9,
+		},
+	},
+	ExpOutputs: map[WireID]uint64{5: 15, 7: 24, 9: 120},
+}
+
+var Circuit12 = TestCircuit{
+	// f(a0,a1,a2,b0,b1) = a0 + a1 + a2 + b0 + b1, where party 0 provides the a's and party 1 the b's
+	Peers: map[PartyID]string{
+		0: "localhost:6660",
+		1: "localhost:6661",
+	},
+	Inputs: map[PartyID]map[GateID]uint64{
+		0: {0: 3200, 1: 4100, 2: 3900},
+		1: {3: 5000, 4: 2800},
+	},
+	Circuit: []Operation{
+		&Input{
+			Party: 0,
+			Out:   0,
+		},
+		&Input{
+			Party: 0,
+			Out:   1,
+		},
+		&Input{
+			Party: 0,
+			Out:   2,
+		},
+		&Input{
+			Party: 1,
+			Out:   3,
+		},
+		&Input{
+			Party: 1,
+			Out:   4,
+		},
+		&Add{
+			In1: 0,
+			In2: 1,
+			Out: 5,
+		},
+		&Add{
+			In1: 5,
+			In2: 2,
+			Out: 6,
+		},
+		&Add{
+			In1: 6,
+			In2: 3,
+			Out: 7,
+		},
+		&Add{
+			In1: 7,
+			In2: 4,
+			Out: 8,
+		},
+		&Reveal{
+			In:  8,
+			Out: 9,
+		},
+	},
+	ExpOutputs: map[WireID]uint64{9: 19000},
+}
+
+var Circuit13 = TestCircuit{
+	// f(a,b,c) = (a + b + c) revealed to party 2 only, a*b revealed to party 0 only and (a + b + c)*2 revealed to all
+	Peers: map[PartyID]string{
+		0: "localhost:6660",
+		1: "localhost:6661",
+		2: "localhost:6662",
+	},
+	Inputs: map[PartyID]map[GateID]uint64{
+		0: {0: 12},
+		1: {1: 30},
+		2: {2: 9},
+	},
+	Circuit: []Operation{
+		&Input{
+			Party: 0,
+			Out:   0,
+		},
+		&Input{
+			Party: 1,
+			Out:   1,
+		},
+		&Input{
+			Party: 2,
+			Out:   2,
+		},
+		&Add{
+			In1: 0,
+			In2: 1,
+			Out: 3,
+		},
+		&Add{
+			In1: 3,
+			In2: 2,
+			Out: 4,
+		},
+		&RevealTo{
+			In:    4,
+			Out:   5,
+			Party: 2,
+		},
+		&Mult{
+			In1: 0,
+			In2: 1,
+			Out: 6,
+		},
+		&RevealTo{
+			In:    6,
+			Out:   7,
+			Party: 0,
+		},
+		&MultCst{
+			In:       4,
+			CstValue: 2,
+			Out:      8,
+		},
+		&Reveal{
+			In:  8,
+			Out: 9,
+		},
+	},
+	ExpOutputs:        map[WireID]uint64{9: 102},
+	ExpPrivateOutputs: map[WireID]uint64{5: 51, 7: 360},
+}
+
+var Circuit14 = TestCircuit{
+	// f(x,y) = (x*y + 3x, sum(x*y)) for vectors x and y of length 4
+	Peers: map[PartyID]string{
+		0: "localhost:6660",
+		1: "localhost:6661",
+	},
+	Inputs: map[PartyID]map[GateID]uint64{
+		0: {0: 1, 1: 2, 2: 3, 3: 4},
+		1: {4: 5, 5: 6, 6: 7, 7: 8},
+	},
+	Circuit: []Operation{
+		&VecInput{
+			Party: 0,
+			Out:   0,
+			Len:   4,
+		},
+		&VecInput{
+			Party: 1,
+			Out:   4,
+			Len:   4,
+		},
+		&VecMult{
+			In1: 0,
+			In2: 4,
+			Out: 8,
+			Len: 4,
+		},
+		&VecMultCst{
+			In:       0,
+			CstValue: 3,
+			Out:      12,
+			Len:      4,
+		},
+		&VecAdd{
+			In1: 8,
+			In2: 12,
+			Out: 16,
+			Len: 4,
+		},
+		&VecReveal{
+			In:  16,
+			Out: 20,
+			Len: 4,
+		},
+		&Add{
+			In1: 8,
+			In2: 9,
+			Out: 24,
+		},
+		&Add{
+			In1: 24,
+			In2: 10,
+			Out: 25,
+		},
+		&Add{
+			In1: 25,
+			In2: 11,
+			Out: 26,
+		},
+		&Reveal{
+			In:  26,
+			Out: 27,
+		},
+	},
+	ExpOutputs: map[WireID]uint64{20: 8, 21: 18, 22: 30, 23: 44, 27: 70},
+}
+
+var Circuit15 = TestCircuit{
+	// f(a,b,c) = (max(a,b,c), [a < b]): sealed-bid auction revealing the highest bid
+	Peers: map[PartyID]string{
+		0: "localhost:6650",
+		1: "localhost:6651",
+		2: "localhost:6652",
+	},
+	Inputs: map[PartyID]map[GateID]uint64{
+		0: {0: 120},
+		1: {1: 345},
+		2: {2: 210},
+	},
+	Circuit: []Operation{
+		&Input{
+			Party: 0,
+			Out:   0,
+		},
+		&Input{
+			Party: 1,
+			Out:   1,
+		},
+		&Input{
+			Party: 2,
+			Out:   2,
+		},
+		&LessThan{
+			In1: 0,
+			In2: 1,
+			Out: 3,
+		},
+		&Sub{
+			In1: 1,
+			In2: 0,
+			Out: 39,
+		},
+		&Mult{
+			In1: 3,
+			In2: 39,
+			Out: 40,
+		},
+		&Add{
+			In1: 0,
+			In2: 40,
+			Out: 41,
+		},
+		&LessThan{
+			In1: 41,
+			In2: 2,
+			Out: 42,
+		},
+		&Sub{
+			In1: 2,
+			In2: 41,
+			Out: 78,
+		},
+		&Mult{
+			In1: 42,
+			In2: 78,
+			Out: 79,
+		},
+		&Add{
+			In1: 41,
+			In2: 79,
+			Out: 80,
+		},
+		&Reveal{
+			In:  80,
+			Out: 81,
+		},
+		&Reveal{
+			In:  3,
+			Out: 82,
+		},
+	},
+	ExpOutputs: map[WireID]uint64{81: 345, 82: 1},
+}
+
+var Circuit16 = TestCircuit{
+	// f(a,b,c) = [a = b] + [a = c] + [b = c]: number of pairs of parties holding the same value
+	Peers: map[PartyID]string{
+		0: "localhost:6650",
+		1: "localhost:6651",
+		2: "localhost:6652",
+	},
+	Inputs: map[PartyID]map[GateID]uint64{
+		0: {0: 4242},
+		1: {1: 1337},
+		2: {2: 4242},
+	},
+	Circuit: []Operation{
+		&Input{
+			Party: 0,
+			Out:   0,
+		},
+		&Input{
+			Party: 1,
+			Out:   1,
+		},
+		&Input{
+			Party: 2,
+			Out:   2,
+		},
+		&Equal{
+			In1: 0,
+			In2: 1,
+			Out: 3,
+		},
+		&Equal{
+			In1: 0,
+			In2: 2,
+			Out: 21,
+		},
+		&Add{
+			In1: 3,
+			In2: 21,
+			Out: 39,
+		},
+		&Equal{
+			In1: 1,
+			In2: 2,
+			Out: 40,
+		},
+		&Add{
+			In1: 39,
+			In2: 40,
+			Out: 58,
+		},
+		&Reveal{
+			In:  58,
+			Out: 59,
+		},
+	},
+	ExpOutputs: map[WireID]uint64{59: 1},
+}
+
+var Circuit17 = TestCircuit{
+	// f(x,y) = (bits of x+y, number of bits of x+y set to 1)
+	Peers: map[PartyID]string{
+		0: "localhost:6650",
+		1: "localhost:6651",
+	},
+	Inputs: map[PartyID]map[GateID]uint64{
+		0: {0: 45000},
+		1: {1: 30000},
+	},
+	Circuit: []Operation{
+		&Input{
+			Party: 0,
+			Out:   0,
+		},
+		&Input{
+			Party: 1,
+			Out:   1,
+		},
+		&Add{
+			In1: 0,
+			In2: 1,
+			Out: 2,
+		},
+		&BitDecompose{
+			In:  2,
+			Out: 3,
+		},
+		&VecReveal{
+			In:  3,
+			Out: 153,
+			Len: 17,
+		},
+		&Add{
+			In1: 3,
+			In2: 4,
+			Out: 170,
+		},
+		&Add{
+			In1: 170,
+			In2: 5,
+			Out: 171,
+		},
+		&Add{
+			In1: 171,
+			In2: 6,
+			Out: 172,
+		},
+		&Add{
+			In1: 172,
+			In2: 7,
+			Out: 173,
+		},
+		&Add{
+			In1: 173,
+			In2: 8,
+			Out: 174,
+		},
+		&Add{
+			In1: 174,
+			In2: 9,
+			Out: 175,
+		},
+		&Add{
+			In1: 175,
+			In2: 10,
+			Out: 176,
+		},
+		&Add{
+			In1: 176,
+			In2: 11,
+			Out: 177,
+		},
+		&Add{
+			In1: 177,
+			In2: 12,
+			Out: 178,
+		},
+		&Add{
+			In1: 178,
+			In2: 13,
+			Out: 179,
+		},
+		&Add{
+			In1: 179,
+			In2: 14,
+			Out: 180,
+		},
+		&Add{
+			In1: 180,
+			In2: 15,
+			Out: 181,
+		},
+		&Add{
+			In1: 181,
+			In2: 16,
+			Out: 182,
+		},
+		&Add{
+			In1: 182,
+			In2: 17,
+			Out: 183,
+		},
+		&Add{
+			In1: 183,
+			In2: 18,
+			Out: 184,
+		},
+		&Add{
+			In1: 184,
+			In2: 19,
+			Out: 185,
+		},
+		&Reveal{
+			In:  185,
+			Out: 186,
+		},
+	},
+	ExpOutputs: map[WireID]uint64{153: 1, 154: 1, 155: 1, 156: 0, 157: 1, 158: 1, 159: 1, 160: 1, 161: 0, 162: 0, 163: 1, 164: 0,
+		165: 0, 166: 1, 167: 0, 168: 0, 169: 0, 186: 9},
+}
+
+var Circuit18 = TestCircuit{
+	// f(x1,x2,w1,w2) = x1*w1 + x2*w2 on fixed-point numbers with 2 fractional bits: 1.5*2.5 - 0.75*2 = 2.25
+	Peers: map[PartyID]string{
+		0: "localhost:6650",
+		1: "localhost:6651",
+	},
+	Inputs: map[PartyID]map[GateID]uint64{
+		0: {0: 6, 1: 65534},
+		1: {2: 10, 3: 8},
+	},
+	Circuit: []Operation{
+		&Input{
+			Party: 0,
+			Out:   0,
+		},
+		&Input{
+			Party: 0,
+			Out:   1,
+		},
+		&Input{
+			Party: 1,
+			Out:   2,
+		},
+		&Input{
+			Party: 1,
+			Out:   3,
+		},
+		&MultFixed{
+			In1:      0,
+			In2:      2,
+			Out:      4,
+			FracBits: 2,
+		},
+		&MultFixed{
+			In1:      1,
+			In2:      3,
+			Out:      20,
+			FracBits: 2,
+		},
+		&Add{
+			In1: 4,
+			In2: 20,
+			Out: 36,
+		},
+		&Reveal{
+			In:  36,
+			Out: 37,
+		},
+	},
+	ExpOutputs: map[WireID]uint64{37: 9},
+}
+
+var Circuit19 = TestCircuit{
+	// f(a,b,n) = (a + b) / n modulo q: (30000 + 12000) / 7 = 6000
+	Peers: map[PartyID]string{
+		0: "localhost:6650",
+		1: "localhost:6651",
+		2: "localhost:6652",
+	},
+	Inputs: map[PartyID]map[GateID]uint64{
+		0: {0: 30000},
+		1: {1: 12000},
+		2: {2: 7},
+	},
+	Circuit: []Operation{
+		&Input{
+			Party: 0,
+			Out:   0,
+		},
+		&Input{
+			Party: 1,
+			Out:   1,
+		},
+		&Input{
+			Party: 2,
+			Out:   2,
+		},
+		&Add{
+			In1: 0,
+			In2: 1,
+			Out: 3,
+		},
+		&Div{
+			In1: 3,
+			In2: 2,
+			Out: 4,
+		},
+		&Reveal{
+			In:  4,
+			Out: 6,
+		},
+	},
+	ExpOutputs: map[WireID]uint64{6: 6000},
+}
+
+var Circuit20 = TestCircuit{
+	// f(x,y,z) = 6 + 6(x+y-z) + 3(x+y-z)^2 + (x+y-z)^3, like Circuit10 but with PowCst gates
+	Peers: map[PartyID]string{
+		0: "localhost:6650",
+		1: "localhost:6651",
+		2: "localhost:6652",
+	},
+	Inputs: map[PartyID]map[GateID]uint64{
+		0: {0: 9},
+		1: {1: 5},
+		2: {2: 7},
+	},
+	Circuit: []Operation{
+		&Input{
+			Party: 0,
+			Out:   0,
+		},
+		&Input{
+			Party: 1,
+			Out:   1,
+		},
+		&Input{
+			Party: 2,
+			Out:   2,
+		},
+		&Add{
+			In1: 0,
+			In2: 1,
+			Out: 3,
+		},
+		&Sub{
+			In1: 3,
+			In2: 2,
+			Out: 4,
+		},
+		&MultCst{
+			In:       4,
+			CstValue: 6,
+			Out:      5,
+		},
+		&AddCst{
+			In:       5,
+			CstValue: 6,
+			Out:      6,
+		},
+		&PowCst{
+			In:       4,
+			Exponent: 2,
+			Out:      7,
+		},
+		&MultCst{
+			In:       7,
+			CstValue: 3,
+			Out:      10,
+		},
+		&Add{
+			In1: 6,
+			In2: 10,
+			Out: 11,
+		},
+		&PowCst{
+			In:       4,
+			Exponent: 3,
+			Out:      12,
+		},
+		&Add{
+			In1: 11,
+			In2: 12,
+			Out: 15,
+		},
+		&Reveal{
+			In:  15,
+			Out: 16,
+		},
+	},
+	ExpOutputs: map[WireID]uint64{16: 538},
+}
+
+var Circuit21 = TestCircuit{
+	// f(x,w) = (x.w, x.(1,2,3,4)) for vectors x and w of length 4, the second inner product having public weights
+	Peers: map[PartyID]string{
+		0: "localhost:6650",
+		1: "localhost:6651",
+	},
+	Inputs: map[PartyID]map[GateID]uint64{
+		0: {0: 3, 1: 1, 2: 4, 3: 1},
+		1: {4: 5, 5: 9, 6: 2, 7: 6},
+	},
+	Circuit: []Operation{
+		&VecInput{
+			Party: 0,
+			Out:   0,
+			Len:   4,
+		},
+		&VecInput{
+			Party: 1,
+			Out:   4,
+			Len:   4,
+		},
+		&DotProduct{
+			In1: []WireID{0, 1, 2, 3},
+			In2: []WireID{4, 5, 6, 7},
+			Out: 8,
+		},
+		&Reveal{
+			In:  8,
+			Out: 12,
+		},
+		&DotProductCst{
+			In:        []WireID{0, 1, 2, 3},
+			CstValues: []uint64{1, 2, 3, 4},
+			Out:       13,
+		},
+		&Reveal{
+			In:  13,
+			Out: 14,
+		},
+	},
+	ExpOutputs: map[WireID]uint64{12: 38, 14: 21},
+}
+
+var Circuit22 = TestCircuit{
+	// f(X,Y) = XY for a 2×3 matrix X and a 3×2 matrix Y, stored row by row
+	Peers: map[PartyID]string{
+		0: "localhost:6650",
+		1: "localhost:6651",
+		2: "localhost:6652",
+	},
+	Inputs: map[PartyID]map[GateID]uint64{
+		0: {0: 1, 1: 2, 2: 3, 3: 4, 4: 5, 5: 6},
+		1: {6: 7, 7: 8, 8: 9, 9: 10, 10: 11, 11: 12},
+	},
+	Circuit: []Operation{
+		&VecInput{
+			Party: 0,
+			Out:   0,
+			Len:   6,
+		},
+		&VecInput{
+			Party: 1,
+			Out:   6,
+			Len:   6,
+		},
+		&MatMul{
+			In1:   0,
+			In2:   6,
+			Out:   12,
+			Rows:  2,
+			Inner: 3,
+			Cols:  2,
+		},
+		&VecReveal{
+			In:  12,
+			Out: 16,
+			Len: 4,
+		},
+	},
+	ExpOutputs: map[WireID]uint64{16: 58, 17: 64, 18: 139, 19: 154},
+}
+
+var Circuit23 = TestCircuit{
+	// f(a,b,c) = (c ? a : b, a < b ? b : a) with c checked to be a bit: (1200, 3400)
+	Peers: map[PartyID]string{
+		0: "localhost:6650",
+		1: "localhost:6651",
+		2: "localhost:6652",
+	},
+	Inputs: map[PartyID]map[GateID]uint64{
+		0: {0: 1200},
+		1: {1: 3400},
+		2: {2: 1},
+	},
+	Circuit: []Operation{
+		&Input{
+			Party: 0,
+			Out:   0,
+		},
+		&Input{
+			Party: 1,
+			Out:   1,
+		},
+		&Input{
+			Party: 2,
+			Out:   2,
+		},
+		&Select{
+			Cond:    2,
+			IfTrue:  0,
+			IfFalse: 1,
+			Out:     3,
+			Check:   true,
+		},
+		&Reveal{
+			In:  3,
+			Out: 5,
+		},
+		&LessThan{
+			In1: 0,
+			In2: 1,
+			Out: 6,
+		},
+		&Select{
+			Cond:    6,
+			IfTrue:  1,
+			IfFalse: 0,
+			Out:     42,
+		},
+		&Reveal{
+			In:  42,
+			Out: 43,
+		},
+	},
+	ExpOutputs: map[WireID]uint64{5: 1200, 43: 3400},
+}
+
+var Circuit24 = TestCircuit{
+	// f(a,b,c) = ((a AND b) XOR NOT c, b OR c) on words of 64 bits
+	Peers: map[PartyID]string{
+		0: "localhost:6650",
+		1: "localhost:6651",
+		2: "localhost:6652",
+	},
+	Inputs: map[PartyID]map[GateID]uint64{
+		0: {0: 0xF0F0F0F0F0F0F0F0},
+		1: {1: 0xFF00FF00FF00FF00},
+		2: {2: 0x0123456789ABCDEF},
+	},
+	Circuit: []Operation{
+		&BoolInput{
+			Party: 0,
+			Out:   0,
+		},
+		&BoolInput{
+			Party: 1,
+			Out:   1,
+		},
+		&BoolInput{
+			Party: 2,
+			Out:   2,
+		},
+		&And{
+			In1: 0,
+			In2: 1,
+			Out: 3,
+		},
+		&Not{
+			In:  2,
+			Out: 4,
+		},
+		&Xor{
+			In1: 3,
+			In2: 4,
+			Out: 5,
+		},
+		&BoolReveal{
+			In:  5,
+			Out: 6,
+		},
+		&Xor{
+			In1: 1,
+			In2: 2,
+			Out: 7,
+		},
+		&And{
+			In1: 1,
+			In2: 2,
+			Out: 8,
+		},
+		&Xor{
+			In1: 7,
+			In2: 8,
+			Out: 9,
+		},
+		&BoolReveal{
+			In:  9,
+			Out: 10,
+		},
+	},
+	ExpOutputs: map[WireID]uint64{6: 0x0EDC4A988654C210, 10: 0xFF23FF67FFABFFEF},
 }
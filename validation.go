@@ -0,0 +1,287 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+type ValidationErrorKind int
+
+const (
+	UndefinedWire        ValidationErrorKind = iota // an operation reads a wire that no operation writes
+	UnorderedWire                                   // an operation reads a wire that is only written afterwards
+	DuplicateWire                                   // a wire is written by more than one operation
+	UnknownParty                                    // an Input or RevealTo gate refers to a party that is not a peer
+	MissingReveal                                   // the circuit never reveals anything, neither publicly nor privately
+	MissingTriplet                                  // a multiplication gate has no Beaver triplet
+	MissingInput                                    // a party has no value for one of its Input gates
+	PrivateWire                                     // an operation reads a wire that is only revealed to one party
+	EmptyVector                                     // a vector operation has no element
+	MissingRandomBit                                // an operation has no preprocessed random bit for one of its wires
+	InvalidFracBits                                 // a MultFixed gate truncates by FixedPointBits bits or more
+	MismatchedLength                                // an inner product has operands of different lengths
+	MissingMatrixTriplet                            // a matrix product has no matrix triplet of its dimensions
+	MismatchedSharing                               // an operation reads a wire of the other sharing, boolean or arithmetic
+	MissingBinaryTriplet                            // an And gate has no binary triplet
+	EmptyOperands                                   // an inner product has no element
+	InvalidLength                                   // a vector operation has a negative length, or a matrix product a dimension below 1
+)
+
+// Problem found in a circuit by ValidateCircuit
+type ValidationError struct {
+	Kind  ValidationErrorKind
+	Index int       // position of the faulty operation in the circuit, -1 if the error concerns the whole circuit
+	Op    Operation // faulty operation, nil if the error concerns the whole circuit
+	Wire  WireID    // concerned wire, if any
+	Party PartyID   // concerned party, if any
+	Value uint64    // faulty parameter of the operation, if any
+}
+
+// Operations whose parameters must be checked, which ValidateCircuit does before reading their wires
+type validatedOperation interface {
+	Operation
+	Validate() *ValidationError // returns the kind of the problem and the faulty parameter, nil if the operation is valid
+}
+
+// Returns the problem of the parameters of the operation at the given position in the circuit, nil if there is none
+func validateOperation(i int, op Operation) *ValidationError {
+	vo, isValidated := op.(validatedOperation)
+	if !isValidated {
+		return nil
+	}
+	err := vo.Validate()
+	if err != nil {
+		err.Index, err.Op, err.Wire = i, op, op.Output()
+	}
+	return err
+}
+
+func (e *ValidationError) Error() string {
+	switch e.Kind {
+	case UndefinedWire:
+		return fmt.Sprintf("operation %d (%T) reads wire %d which is never written", e.Index, e.Op, e.Wire)
+	case UnorderedWire:
+		return fmt.Sprintf("operation %d (%T) reads wire %d before it is written", e.Index, e.Op, e.Wire)
+	case DuplicateWire:
+		return fmt.Sprintf("operation %d (%T) writes wire %d which is already written", e.Index, e.Op, e.Wire)
+	case UnknownParty:
+		return fmt.Sprintf("operation %d (%T) belongs to unknown party %d", e.Index, e.Op, e.Party)
+	case MissingReveal:
+		return "circuit has no Reveal nor RevealTo gate"
+	case MissingTriplet:
+		return fmt.Sprintf("operation %d (%T) has no Beaver triplet for wire %d", e.Index, e.Op, e.Wire)
+	case PrivateWire:
+		return fmt.Sprintf("operation %d (%T) reads wire %d which is only revealed to party %d", e.Index, e.Op, e.Wire, e.Party)
+	case MissingMatrixTriplet:
+		return fmt.Sprintf("operation %d (%T) has no matrix triplet of its dimensions", e.Index, e.Op)
+	case MismatchedSharing:
+		return fmt.Sprintf("operation %d (%T) reads wire %d which is shared in another way", e.Index, e.Op, e.Wire)
+	case MissingBinaryTriplet:
+		return fmt.Sprintf("operation %d (%T) has no binary triplet", e.Index, e.Op)
+	case MissingRandomBit:
+		return fmt.Sprintf("operation %d (%T) has no random bit for wire %d", e.Index, e.Op, e.Wire)
+	case InvalidFracBits:
+		return fmt.Sprintf("operation %d (%T) truncates by %d bits, at most %d are supported", e.Index, e.Op, e.Value, FixedPointBits-1)
+	case MismatchedLength:
+		return fmt.Sprintf("operation %d (%T) has operands of different lengths", e.Index, e.Op)
+	case InvalidLength:
+		return fmt.Sprintf("operation %d (%T) has an invalid length or dimension", e.Index, e.Op)
+	case EmptyOperands:
+		return fmt.Sprintf("operation %d (%T) has empty operands", e.Index, e.Op)
+	case EmptyVector:
+		return fmt.Sprintf("operation %d (%T) has an empty output vector", e.Index, e.Op)
+	case MissingInput:
+		return fmt.Sprintf("operation %d (%T) has no input value from party %d", e.Index, e.Op, e.Party)
+	default:
+		return fmt.Sprintf("operation %d (%T) is invalid", e.Index, e.Op)
+	}
+}
+
+// All the problems found in a circuit, in the order of the operations
+type ValidationErrors []*ValidationError
+
+func (errs ValidationErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("invalid circuit: %s", strings.Join(msgs, "; "))
+}
+
+// Check that the circuit can be evaluated by the given peers: each wire is written exactly once before being read,
+// Input and RevealTo gates refer to known parties, the private outputs are not read by other operations and the
+// circuit reveals at least one value, publicly (Reveal) or privately (RevealTo), and the boolean gates only read
+// boolean wires and the other gates arithmetic wires. If 'beaverTriplets' is not nil, also check that each
+// multiplication gate has its triplet. Returns nil if the circuit is valid, ValidationErrors otherwise
+func ValidateCircuit(circuit Circuit, peers map[PartyID]string, beaverTriplets map[WireID]BeaverTriplet) error {
+	var errs ValidationErrors
+
+	// The wires of the operations with invalid parameters may not be computable, only their output wire is defined
+	invalid := make(map[int]*ValidationError)
+	written := make(map[WireID]int, len(circuit))
+	for i, op := range circuit {
+		if err := validateOperation(i, op); err != nil {
+			invalid[i] = err
+			if _, exists := written[op.Output()]; !exists {
+				written[op.Output()] = i
+			}
+			continue
+		}
+		for _, w := range outputWires(op) {
+			if _, exists := written[w]; !exists {
+				written[w] = i
+			}
+		}
+	}
+
+	revealed := false
+	defined := make(map[WireID]bool, len(circuit))
+	private := make(map[WireID]PartyID)
+	boolean := make(map[WireID]bool)
+	for i, op := range circuit {
+		if err, isInvalid := invalid[i]; isInvalid {
+			errs = append(errs, err)
+			defined[op.Output()] = true
+			boolean[op.Output()] = isBoolean(op)
+			continue
+		}
+
+		for _, in := range op.Inputs() {
+			if defined[in] && boolean[in] != isBoolean(op) {
+				errs = append(errs, &ValidationError{Kind: MismatchedSharing, Index: i, Op: op, Wire: in})
+			}
+			if party, isPrivate := private[in]; isPrivate {
+				errs = append(errs, &ValidationError{Kind: PrivateWire, Index: i, Op: op, Wire: in, Party: party})
+			} else if !defined[in] {
+				kind := UnorderedWire
+				if _, exists := written[in]; !exists {
+					kind = UndefinedWire
+				}
+				errs = append(errs, &ValidationError{Kind: kind, Index: i, Op: op, Wire: in})
+			}
+		}
+
+		if len(outputWires(op)) == 0 {
+			errs = append(errs, &ValidationError{Kind: EmptyVector, Index: i, Op: op, Wire: op.Output()})
+		}
+		for _, w := range outputWires(op) {
+			if defined[w] {
+				errs = append(errs, &ValidationError{Kind: DuplicateWire, Index: i, Op: op, Wire: w})
+			}
+			defined[w] = true
+			boolean[w] = isBoolean(op)
+		}
+
+		if in, isInput := op.(inputOperation); isInput {
+			if _, known := peers[in.Owner()]; !known {
+				errs = append(errs, &ValidationError{Kind: UnknownParty, Index: i, Op: op, Party: in.Owner()})
+			}
+		}
+		if ro, isPrivate := op.(privateOpeningOperation); isPrivate {
+			if _, known := peers[ro.Recipient()]; !known {
+				errs = append(errs, &ValidationError{Kind: UnknownParty, Index: i, Op: op, Party: ro.Recipient()})
+			}
+			private[op.Output()] = ro.Recipient()
+			revealed = true
+		}
+		if len(Circuit{op}.OutputWires()) > 0 {
+			revealed = true
+		}
+
+		if beaverTriplets != nil {
+			for _, w := range tripletWires(op) {
+				if _, exists := beaverTriplets[w]; !exists {
+					errs = append(errs, &ValidationError{Kind: MissingTriplet, Index: i, Op: op, Wire: w})
+				}
+			}
+		}
+	}
+
+	if !revealed {
+		errs = append(errs, &ValidationError{Kind: MissingReveal, Index: -1})
+	}
+
+	if errs != nil {
+		return errs
+	}
+	return nil
+}
+
+// Check that 'inputs' holds a value for each Input gate of the circuit owned by 'party'. Returns nil if it is the
+// case, ValidationErrors otherwise
+func ValidateInputs(circuit Circuit, party PartyID, inputs map[GateID]uint64) error {
+	var errs ValidationErrors
+	for i, op := range circuit {
+		if in, isInput := op.(inputOperation); isInput && in.Owner() == party {
+			for _, w := range outputWires(op) {
+				if _, exists := inputs[GateID(w)]; !exists {
+					errs = append(errs, &ValidationError{Kind: MissingInput, Index: i, Op: op, Wire: w, Party: party})
+				}
+			}
+		}
+	}
+
+	if errs != nil {
+		return errs
+	}
+	return nil
+}
+
+// Check that 'randomBits' holds a random bit for each wire keying the random bits of an operation of the circuit.
+// Returns nil if it is the case, ValidationErrors otherwise
+func ValidateRandomBits(circuit Circuit, randomBits map[WireID]*big.Int) error {
+	var errs ValidationErrors
+	for i, op := range circuit {
+		if ro, isRandom := op.(randomBitsOperation); isRandom {
+			for _, w := range ro.RandomBitWires() {
+				if _, exists := randomBits[w]; !exists {
+					errs = append(errs, &ValidationError{Kind: MissingRandomBit, Index: i, Op: op, Wire: w})
+				}
+			}
+		}
+	}
+
+	if errs != nil {
+		return errs
+	}
+	return nil
+}
+
+// Check that 'matrixTriplets' holds a matrix triplet of the right dimensions for each matrix product of the circuit,
+// keyed by its output wire. Returns nil if it is the case, ValidationErrors otherwise
+func ValidateMatrixTriplets(circuit Circuit, matrixTriplets map[WireID]MatrixTriplet) error {
+	var errs ValidationErrors
+	for i, op := range circuit {
+		if mo, isMatrix := op.(matrixTripletOperation); isMatrix {
+			n, k, m := mo.dimensions()
+			triplet, exists := matrixTriplets[op.Output()]
+			if !exists || len(triplet.a) != n*k || len(triplet.b) != k*m || len(triplet.c) != n*m {
+				errs = append(errs, &ValidationError{Kind: MissingMatrixTriplet, Index: i, Op: op, Wire: op.Output()})
+			}
+		}
+	}
+
+	if errs != nil {
+		return errs
+	}
+	return nil
+}
+
+// Check that 'binaryTriplets' holds a binary triplet for each And gate of the circuit, keyed by its output wire.
+// Returns nil if it is the case, ValidationErrors otherwise
+func ValidateBinaryTriplets(circuit Circuit, binaryTriplets map[WireID]BinaryTriplet) error {
+	var errs ValidationErrors
+	for i, op := range circuit {
+		if _, isBinary := op.(binaryTripletOperation); isBinary {
+			if _, exists := binaryTriplets[op.Output()]; !exists {
+				errs = append(errs, &ValidationError{Kind: MissingBinaryTriplet, Index: i, Op: op, Wire: op.Output()})
+			}
+		}
+	}
+
+	if errs != nil {
+		return errs
+	}
+	return nil
+}
@@ -0,0 +1,485 @@
+package main
+
+import (
+	"math/big"
+)
+
+// Operations on vector wires. A vector of length Length() at wire w is stored element-wise in the wires w to
+// w+Length()-1, so that its elements are shared, opened and revealed like scalar wires, and can be read by scalar gates
+type vectorOperation interface {
+	Operation
+	Length() int
+}
+
+// Returns the wires of the elements of the vector of length n at wire w
+func vectorWires(w WireID, n int) []WireID {
+	wires := make([]WireID, n)
+	for i := range wires {
+		wires[i] = w + WireID(i)
+	}
+	return wires
+}
+
+// Operations storing intermediate values in internal wires: an operation with output wire w and k internal wires
+// also writes the wires w+1 to w+k, which are not meant to be read by other operations
+type internalWiresOperation interface {
+	Operation
+	InternalWires() int
+}
+
+// Returns the wires written by the operation: the output wire of a scalar operation followed by its internal wires,
+// or the elements of the output vector of a vector operation
+func outputWires(op Operation) []WireID {
+	if vo, isVector := op.(vectorOperation); isVector {
+		return vectorWires(op.Output(), vo.Length())
+	}
+	if io, isInternal := op.(internalWiresOperation); isInternal {
+		return vectorWires(op.Output(), 1+io.InternalWires())
+	}
+	return []WireID{op.Output()}
+}
+
+// Returns the wires keying the Beaver triplets consumed by the operation: the wires given by an operation needing
+// several triplets, one per element of a vector multiplication, the output wire of a scalar multiplication, none
+// otherwise
+func tripletWires(op Operation) []WireID {
+	if to, isTriplets := op.(tripletOperation); isTriplets {
+		return to.TripletWires()
+	}
+	if !op.IsMult() {
+		return nil
+	}
+	return outputWires(op)
+}
+
+// Generate the Beaver triplets of all the multiplications of the circuit, for the parties 0 to parties-1
+func DealBeaverTriplets(circuit Circuit, parties int) map[PartyID]map[WireID]BeaverTriplet {
+	beaverTriplets := make(map[PartyID]map[WireID]BeaverTriplet, parties)
+	for id := 0; id < parties; id++ {
+		beaverTriplets[PartyID(id)] = make(map[WireID]BeaverTriplet)
+	}
+	for _, op := range circuit {
+		for _, w := range tripletWires(op) {
+			for id, triplet := range op.BeaverTriplet(parties) {
+				beaverTriplets[PartyID(id)][w] = triplet
+			}
+		}
+	}
+	return beaverTriplets
+}
+
+// Evaluate an opening operation on its own, opening all its values in a single round
+func evalOpening(cep *Protocol, oo openingOperation) {
+	recipient := AllParties
+	if po, isPrivate := oo.(privateOpeningOperation); isPrivate {
+		recipient = po.Recipient()
+	}
+	shares := oo.Shares(cep)
+	recipients := make([]PartyID, len(shares))
+	binary := make([]bool, len(shares))
+	for i := range recipients {
+		recipients[i] = recipient
+		binary[i] = isBoolean(oo)
+	}
+	oo.Open(cep, cep.openShares(shares, recipients, binary))
+}
+
+// Returns an error if the length of a vector is negative. An empty vector is reported as EmptyVector instead
+func validateLength(n int) *ValidationError {
+	if n < 0 {
+		return &ValidationError{Kind: InvalidLength}
+	}
+	return nil
+}
+
+// Vector of Len inputs provided by the party, keyed by the wires Out to Out+Len-1 in its inputs
+type VecInput struct {
+	Party PartyID
+	Out   WireID
+	Len   int
+}
+
+func (vio VecInput) Validate() *ValidationError {
+	return validateLength(vio.Len)
+}
+
+func (vio VecInput) IsMult() bool {
+	return false
+}
+
+func (vio VecInput) Output() WireID {
+	return vio.Out
+}
+
+func (vio VecInput) Inputs() []WireID {
+	return nil
+}
+
+func (vio VecInput) Length() int {
+	return vio.Len
+}
+
+func (vio VecInput) Remap(f func(WireID) WireID) Operation {
+	return &VecInput{Party: vio.Party, Out: f(vio.Out), Len: vio.Len}
+}
+
+func (vio VecInput) Owner() PartyID {
+	return vio.Party
+}
+
+// Split each element of the input between the peers: keep our shares and return the shares of each peer
+func (vio VecInput) generateShares(cep *Protocol) map[PartyID][]*big.Int {
+	shares := make(map[PartyID][]*big.Int, len(cep.Peers))
+	for _, w := range vectorWires(vio.Out, vio.Len) {
+		for id, share := range (Input{Party: vio.Party, Out: w}).generateShares(cep) {
+			shares[id] = append(shares[id], share...)
+		}
+	}
+	return shares
+}
+
+// Share all the elements of the input in a single round
+func (vio VecInput) Eval(cep *Protocol) {
+	cep.shareInputs([]Operation{vio})
+}
+
+func (vio VecInput) EvalClear(ce *ClearEvaluation) {
+	for _, w := range vectorWires(vio.Out, vio.Len) {
+		Input{Party: vio.Party, Out: w}.EvalClear(ce)
+	}
+}
+
+func (vio VecInput) BeaverTriplet(count int) []BeaverTriplet {
+	return nil
+}
+
+// Element-wise addition of two vectors
+type VecAdd struct {
+	In1 WireID
+	In2 WireID
+	Out WireID
+	Len int
+}
+
+func (vao VecAdd) Validate() *ValidationError {
+	return validateLength(vao.Len)
+}
+
+func (vao VecAdd) IsMult() bool {
+	return false
+}
+
+func (vao VecAdd) Output() WireID {
+	return vao.Out
+}
+
+func (vao VecAdd) Inputs() []WireID {
+	return append(vectorWires(vao.In1, vao.Len), vectorWires(vao.In2, vao.Len)...)
+}
+
+func (vao VecAdd) Length() int {
+	return vao.Len
+}
+
+func (vao VecAdd) Remap(f func(WireID) WireID) Operation {
+	return &VecAdd{In1: f(vao.In1), In2: f(vao.In2), Out: f(vao.Out), Len: vao.Len}
+}
+
+func (vao VecAdd) Eval(cep *Protocol) {
+	for i := 0; i < vao.Len; i++ {
+		Add{In1: vao.In1 + WireID(i), In2: vao.In2 + WireID(i), Out: vao.Out + WireID(i)}.Eval(cep)
+	}
+}
+
+func (vao VecAdd) EvalClear(ce *ClearEvaluation) {
+	for i := 0; i < vao.Len; i++ {
+		Add{In1: vao.In1 + WireID(i), In2: vao.In2 + WireID(i), Out: vao.Out + WireID(i)}.EvalClear(ce)
+	}
+}
+
+func (vao VecAdd) BeaverTriplet(count int) []BeaverTriplet {
+	return nil
+}
+
+// Element-wise subtraction of two vectors
+type VecSub struct {
+	In1 WireID
+	In2 WireID
+	Out WireID
+	Len int
+}
+
+func (vso VecSub) Validate() *ValidationError {
+	return validateLength(vso.Len)
+}
+
+func (vso VecSub) IsMult() bool {
+	return false
+}
+
+func (vso VecSub) Output() WireID {
+	return vso.Out
+}
+
+func (vso VecSub) Inputs() []WireID {
+	return append(vectorWires(vso.In1, vso.Len), vectorWires(vso.In2, vso.Len)...)
+}
+
+func (vso VecSub) Length() int {
+	return vso.Len
+}
+
+func (vso VecSub) Remap(f func(WireID) WireID) Operation {
+	return &VecSub{In1: f(vso.In1), In2: f(vso.In2), Out: f(vso.Out), Len: vso.Len}
+}
+
+func (vso VecSub) Eval(cep *Protocol) {
+	for i := 0; i < vso.Len; i++ {
+		Sub{In1: vso.In1 + WireID(i), In2: vso.In2 + WireID(i), Out: vso.Out + WireID(i)}.Eval(cep)
+	}
+}
+
+func (vso VecSub) EvalClear(ce *ClearEvaluation) {
+	for i := 0; i < vso.Len; i++ {
+		Sub{In1: vso.In1 + WireID(i), In2: vso.In2 + WireID(i), Out: vso.Out + WireID(i)}.EvalClear(ce)
+	}
+}
+
+func (vso VecSub) BeaverTriplet(count int) []BeaverTriplet {
+	return nil
+}
+
+// Addition of a constant to each element of a vector
+type VecAddCst struct {
+	In       WireID
+	CstValue uint64
+	Out      WireID
+	Len      int
+}
+
+func (vaco VecAddCst) Validate() *ValidationError {
+	return validateLength(vaco.Len)
+}
+
+func (vaco VecAddCst) IsMult() bool {
+	return false
+}
+
+func (vaco VecAddCst) Output() WireID {
+	return vaco.Out
+}
+
+func (vaco VecAddCst) Inputs() []WireID {
+	return vectorWires(vaco.In, vaco.Len)
+}
+
+func (vaco VecAddCst) Length() int {
+	return vaco.Len
+}
+
+func (vaco VecAddCst) Remap(f func(WireID) WireID) Operation {
+	return &VecAddCst{In: f(vaco.In), CstValue: vaco.CstValue, Out: f(vaco.Out), Len: vaco.Len}
+}
+
+func (vaco VecAddCst) Eval(cep *Protocol) {
+	for i := 0; i < vaco.Len; i++ {
+		AddCst{In: vaco.In + WireID(i), CstValue: vaco.CstValue, Out: vaco.Out + WireID(i)}.Eval(cep)
+	}
+}
+
+func (vaco VecAddCst) EvalClear(ce *ClearEvaluation) {
+	for i := 0; i < vaco.Len; i++ {
+		AddCst{In: vaco.In + WireID(i), CstValue: vaco.CstValue, Out: vaco.Out + WireID(i)}.EvalClear(ce)
+	}
+}
+
+func (vaco VecAddCst) BeaverTriplet(count int) []BeaverTriplet {
+	return nil
+}
+
+// Multiplication of each element of a vector by a constant
+type VecMultCst struct {
+	In       WireID
+	CstValue uint64
+	Out      WireID
+	Len      int
+}
+
+func (vmco VecMultCst) Validate() *ValidationError {
+	return validateLength(vmco.Len)
+}
+
+func (vmco VecMultCst) IsMult() bool {
+	return false
+}
+
+func (vmco VecMultCst) Output() WireID {
+	return vmco.Out
+}
+
+func (vmco VecMultCst) Inputs() []WireID {
+	return vectorWires(vmco.In, vmco.Len)
+}
+
+func (vmco VecMultCst) Length() int {
+	return vmco.Len
+}
+
+func (vmco VecMultCst) Remap(f func(WireID) WireID) Operation {
+	return &VecMultCst{In: f(vmco.In), CstValue: vmco.CstValue, Out: f(vmco.Out), Len: vmco.Len}
+}
+
+func (vmco VecMultCst) Eval(cep *Protocol) {
+	for i := 0; i < vmco.Len; i++ {
+		MultCst{In: vmco.In + WireID(i), CstValue: vmco.CstValue, Out: vmco.Out + WireID(i)}.Eval(cep)
+	}
+}
+
+func (vmco VecMultCst) EvalClear(ce *ClearEvaluation) {
+	for i := 0; i < vmco.Len; i++ {
+		MultCst{In: vmco.In + WireID(i), CstValue: vmco.CstValue, Out: vmco.Out + WireID(i)}.EvalClear(ce)
+	}
+}
+
+func (vmco VecMultCst) BeaverTriplet(count int) []BeaverTriplet {
+	return nil
+}
+
+// Element-wise multiplication of two vectors, consuming one Beaver triplet per element, keyed by the output wire of
+// the element. All the masked elements are opened in a single round
+type VecMult struct {
+	In1 WireID
+	In2 WireID
+	Out WireID
+	Len int
+}
+
+func (vmo VecMult) Validate() *ValidationError {
+	return validateLength(vmo.Len)
+}
+
+func (vmo VecMult) IsMult() bool {
+	return true
+}
+
+func (vmo VecMult) Output() WireID {
+	return vmo.Out
+}
+
+func (vmo VecMult) Inputs() []WireID {
+	return append(vectorWires(vmo.In1, vmo.Len), vectorWires(vmo.In2, vmo.Len)...)
+}
+
+func (vmo VecMult) Length() int {
+	return vmo.Len
+}
+
+func (vmo VecMult) Remap(f func(WireID) WireID) Operation {
+	return &VecMult{In1: f(vmo.In1), In2: f(vmo.In2), Out: f(vmo.Out), Len: vmo.Len}
+}
+
+func (vmo VecMult) element(i int) Mult {
+	return Mult{In1: vmo.In1 + WireID(i), In2: vmo.In2 + WireID(i), Out: vmo.Out + WireID(i)}
+}
+
+func (vmo VecMult) Eval(cep *Protocol) {
+	evalOpening(cep, vmo)
+}
+
+func (vmo VecMult) EvalClear(ce *ClearEvaluation) {
+	for i := 0; i < vmo.Len; i++ {
+		vmo.element(i).EvalClear(ce)
+	}
+}
+
+// Returns our shares of x_i-a_i and y_i-b_i for each element i
+func (vmo VecMult) Shares(cep *Protocol) []*big.Int {
+	shares := make([]*big.Int, 0, 2*vmo.Len)
+	for i := 0; i < vmo.Len; i++ {
+		shares = append(shares, vmo.element(i).Shares(cep)...)
+	}
+	return shares
+}
+
+// Computes our share of x_i*y_i for each element i
+func (vmo VecMult) Open(cep *Protocol, opened []*big.Int) {
+	for i := 0; i < vmo.Len; i++ {
+		vmo.element(i).Open(cep, opened[2*i:2*i+2])
+	}
+}
+
+func (vmo VecMult) OpenCount() int {
+	return 2 * vmo.Len
+}
+
+// Returns the shares of one triplet: the dealer calls it once per element (see tripletWires)
+func (vmo VecMult) BeaverTriplet(count int) []BeaverTriplet {
+	return Mult{}.BeaverTriplet(count)
+}
+
+// Reveal all the elements of a vector in a single round
+type VecReveal struct {
+	In  WireID
+	Out WireID
+	Len int
+}
+
+func (vro VecReveal) Validate() *ValidationError {
+	return validateLength(vro.Len)
+}
+
+func (vro VecReveal) IsMult() bool {
+	return false
+}
+
+func (vro VecReveal) Output() WireID {
+	return vro.Out
+}
+
+func (vro VecReveal) Inputs() []WireID {
+	return vectorWires(vro.In, vro.Len)
+}
+
+func (vro VecReveal) Length() int {
+	return vro.Len
+}
+
+func (vro VecReveal) Remap(f func(WireID) WireID) Operation {
+	return &VecReveal{In: f(vro.In), Out: f(vro.Out), Len: vro.Len}
+}
+
+func (vro VecReveal) element(i int) Reveal {
+	return Reveal{In: vro.In + WireID(i), Out: vro.Out + WireID(i)}
+}
+
+func (vro VecReveal) Eval(cep *Protocol) {
+	evalOpening(cep, vro)
+}
+
+func (vro VecReveal) EvalClear(ce *ClearEvaluation) {
+	for i := 0; i < vro.Len; i++ {
+		vro.element(i).EvalClear(ce)
+	}
+}
+
+func (vro VecReveal) Shares(cep *Protocol) []*big.Int {
+	shares := make([]*big.Int, vro.Len)
+	for i := range shares {
+		shares[i] = vro.element(i).Shares(cep)[0]
+	}
+	return shares
+}
+
+func (vro VecReveal) Open(cep *Protocol, opened []*big.Int) {
+	for i := 0; i < vro.Len; i++ {
+		vro.element(i).Open(cep, opened[i:i+1])
+	}
+}
+
+func (vro VecReveal) OpenCount() int {
+	return vro.Len
+}
+
+func (vro VecReveal) BeaverTriplet(count int) []BeaverTriplet {
+	return nil
+}